@@ -0,0 +1,216 @@
+package slogx
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+type (
+	// batchEntry is an encoded record waiting to be sent.
+	batchEntry struct {
+		time time.Time
+		data []byte
+	}
+
+	// batchOptions configures batcher. Zero values select the defaults.
+	batchOptions struct {
+		maxEntries    int           // entries per batch, 1000 by default
+		maxBytes      int           // bytes per batch, 1MiB by default
+		entryOverhead int           // bytes added to the size of each entry
+		maxBuffered   int           // bytes kept while sending is slow or failing, 8 batches by default
+		interval      time.Duration // 5s by default
+		timeout       time.Duration // per batch send, 30s by default
+		retries       int           // attempts after a failed send, 3 by default
+		clock         Clock
+	}
+
+	// batcher collects encoded records and sends them in batches bounded by
+	// count and size, from a background goroutine, every interval or as soon
+	// as a batch is full.
+	batcher struct {
+		opts batchOptions
+		send func(context.Context, []batchEntry) error
+
+		mu      sync.Mutex
+		entries []batchEntry
+		size    int
+
+		sendMu  sync.Mutex
+		full    chan struct{}
+		stop    chan struct{}
+		stopped chan struct{}
+		once    sync.Once
+
+		unregister func() // removes the batcher from FlushAll
+
+		statsCounters
+	}
+)
+
+// permanentError is returned by batcher send functions for failures retrying
+// would not fix, e.g. a rejected request.
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+func newBatcher(opts batchOptions, send func(context.Context, []batchEntry) error) *batcher {
+	if opts.maxEntries <= 0 {
+		opts.maxEntries = 1000
+	}
+	if opts.maxBytes <= 0 {
+		opts.maxBytes = 1 << 20
+	}
+	if opts.maxBuffered <= 0 {
+		opts.maxBuffered = 8 * opts.maxBytes
+	}
+	if opts.interval <= 0 {
+		opts.interval = 5 * time.Second
+	}
+	if opts.timeout <= 0 {
+		opts.timeout = 30 * time.Second
+	}
+	if opts.retries <= 0 {
+		opts.retries = 3
+	}
+	opts.clock = clockOrSystem(opts.clock)
+	b := &batcher{
+		opts:    opts,
+		send:    send,
+		full:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	b.unregister = RegisterFlusher(b)
+	go b.run()
+	return b
+}
+
+// add queues a copy of data. It drops the entry when too much is buffered already.
+func (b *batcher) add(t time.Time, data []byte) {
+	size := len(data) + b.opts.entryOverhead
+
+	b.mu.Lock()
+	if b.size+size > b.opts.maxBuffered {
+		b.mu.Unlock()
+		b.dropped.Add(1)
+		return
+	}
+	b.entries = append(b.entries, batchEntry{time: t, data: append([]byte(nil), data...)})
+	b.size += size
+	full := len(b.entries) >= b.opts.maxEntries || b.size >= b.opts.maxBytes
+	b.mu.Unlock()
+
+	if full {
+		select {
+		case b.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (b *batcher) run() {
+	defer close(b.stopped)
+	ticker := time.NewTicker(b.opts.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-ticker.C:
+		case <-b.full:
+		}
+		_ = b.Flush()
+	}
+}
+
+// Flush sends all the queued entries and returns the last send error.
+func (b *batcher) Flush() error {
+	b.sendMu.Lock()
+	defer b.sendMu.Unlock()
+
+	b.mu.Lock()
+	entries := b.entries
+	b.entries, b.size = nil, 0
+	b.mu.Unlock()
+
+	var err error
+	for len(entries) > 0 {
+		n := b.chunkLen(entries)
+		if e := b.sendWithRetries(entries[:n]); e != nil {
+			err = e
+		}
+		entries = entries[n:]
+	}
+	return err
+}
+
+// Stats returns the delivery counters with the number of queued entries.
+func (b *batcher) Stats() Stats {
+	s := b.statsCounters.Stats()
+	b.mu.Lock()
+	s.Queued = len(b.entries)
+	b.mu.Unlock()
+	return s
+}
+
+// Close stops the background goroutine, removes the batcher from FlushAll
+// and sends the queued entries.
+func (b *batcher) Close() error {
+	b.once.Do(func() {
+		close(b.stop)
+		b.unregister()
+	})
+	<-b.stopped
+	return b.Flush()
+}
+
+// chunkLen returns the number of leading entries fitting in one batch.
+func (b *batcher) chunkLen(entries []batchEntry) int {
+	size := 0
+	for i, e := range entries {
+		size += len(e.data) + b.opts.entryOverhead
+		if i == b.opts.maxEntries || (i > 0 && size > b.opts.maxBytes) {
+			return i
+		}
+	}
+	return len(entries)
+}
+
+func (b *batcher) sendWithRetries(entries []batchEntry) error {
+	delay := 100 * time.Millisecond
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), b.opts.timeout)
+		err := b.send(ctx, entries)
+		cancel()
+		if err == nil {
+			if attempt == 0 {
+				b.handled.Add(uint64(len(entries)))
+			} else {
+				b.retried.Add(uint64(len(entries)))
+			}
+			b.setFlushed(b.opts.clock.Now())
+			return nil
+		}
+		b.setError(err, b.opts.clock.Now())
+		if attempt == b.opts.retries || errors.As(err, new(permanentError)) {
+			b.dropped.Add(uint64(len(entries)))
+			return err
+		}
+		select {
+		case <-time.After(delay):
+			delay *= 2
+		case <-b.stop:
+			// closing, make the remaining attempts without waiting
+		}
+	}
+}
@@ -0,0 +1,344 @@
+package slogx
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+)
+
+// BinaryFormat is the wire encoding of BinaryHandler records.
+type BinaryFormat int
+
+const (
+	MessagePack BinaryFormat = iota
+	CBOR
+)
+
+// BinaryHandler writes each record as a single MessagePack or CBOR map with
+// the time, level, source and msg keys followed by the record attributes,
+// as slog.JSONHandler orders them.
+// Groups are encoded as nested maps. Records are self-delimiting, so a stream
+// of them can be read back with BinaryDecoder.
+type BinaryHandler struct {
+	opts   slog.HandlerOptions
+	format BinaryFormat
+	state  attrState
+	mu     *sync.Mutex
+	w      io.Writer
+}
+
+var _ slog.Handler = (*BinaryHandler)(nil)
+
+var bufPool = sync.Pool{
+	New: func() any {
+		b := make([]byte, 0, 1024)
+		return &b
+	},
+}
+
+// NewBinaryHandler creates BinaryHandler writing records to w in the given format
+func NewBinaryHandler(w io.Writer, format BinaryFormat, opts *slog.HandlerOptions) *BinaryHandler {
+	h := &BinaryHandler{
+		format: format,
+		mu:     new(sync.Mutex),
+		w:      w,
+	}
+	if opts != nil {
+		h.opts = *opts
+	}
+	return h
+}
+
+func (h *BinaryHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return levelEnabled(&h.opts, level)
+}
+
+func (h *BinaryHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.state = h.state.withAttrs(attrs)
+	return &h2
+}
+
+func (h *BinaryHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.state = h.state.withGroup(name)
+	return &h2
+}
+
+func (h *BinaryHandler) Handle(_ context.Context, r slog.Record) error {
+	xs := append(builtinAttrs(r, &h.opts, nil), h.state.attrs(r, h.opts.ReplaceAttr)...)
+
+	buf := bufPool.Get().(*[]byte)
+	b := appendBinaryAttrs(binaryEncoderOf(h.format), (*buf)[:0], xs)
+
+	h.mu.Lock()
+	_, err := h.w.Write(b)
+	h.mu.Unlock()
+
+	if cap(b) <= 64<<10 {
+		*buf = b
+		bufPool.Put(buf)
+	}
+	return err
+}
+
+type binaryEncoder interface {
+	appendMap(b []byte, n int) []byte
+	appendArray(b []byte, n int) []byte
+	appendString(b []byte, s string) []byte
+	appendBytes(b []byte, p []byte) []byte
+	appendInt(b []byte, v int64) []byte
+	appendUint(b []byte, v uint64) []byte
+	appendFloat(b []byte, v float64) []byte
+	appendBool(b []byte, v bool) []byte
+	appendNil(b []byte) []byte
+	appendTime(b []byte, t time.Time) []byte
+}
+
+func binaryEncoderOf(format BinaryFormat) binaryEncoder {
+	if format == CBOR {
+		return cborEncoder{}
+	}
+	return msgpackEncoder{}
+}
+
+func appendBinaryAttrs(enc binaryEncoder, b []byte, attrs []slog.Attr) []byte {
+	b = enc.appendMap(b, len(attrs))
+	for _, a := range attrs {
+		b = enc.appendString(b, a.Key)
+		b = appendBinaryValue(enc, b, a.Value)
+	}
+	return b
+}
+
+func appendBinaryValue(enc binaryEncoder, b []byte, v slog.Value) []byte {
+	switch v.Kind() {
+	case slog.KindString:
+		return enc.appendString(b, v.String())
+	case slog.KindInt64:
+		return enc.appendInt(b, v.Int64())
+	case slog.KindUint64:
+		return enc.appendUint(b, v.Uint64())
+	case slog.KindFloat64:
+		return enc.appendFloat(b, v.Float64())
+	case slog.KindBool:
+		return enc.appendBool(b, v.Bool())
+	case slog.KindDuration:
+		return enc.appendInt(b, int64(v.Duration()))
+	case slog.KindTime:
+		return enc.appendTime(b, v.Time())
+	case slog.KindGroup:
+		return appendBinaryAttrs(enc, b, v.Group())
+	case slog.KindLogValuer:
+		return appendBinaryValue(enc, b, v.Resolve())
+	}
+	return appendBinaryAny(enc, b, v.Any())
+}
+
+func appendBinaryAny(enc binaryEncoder, b []byte, x any) []byte {
+	switch x := x.(type) {
+	case nil:
+		return enc.appendNil(b)
+	case []byte:
+		return enc.appendBytes(b, x)
+	case error:
+		return enc.appendString(b, x.Error())
+	case slog.Level:
+		return enc.appendString(b, x.String())
+	case []any:
+		b = enc.appendArray(b, len(x))
+		for _, y := range x {
+			b = appendBinaryAny(enc, b, y)
+		}
+		return b
+	case []string:
+		b = enc.appendArray(b, len(x))
+		for _, y := range x {
+			b = enc.appendString(b, y)
+		}
+		return b
+	case map[string]any:
+		b = enc.appendMap(b, len(x))
+		for k, y := range x {
+			b = enc.appendString(b, k)
+			b = appendBinaryAny(enc, b, y)
+		}
+		return b
+	}
+	if v := slog.AnyValue(x); v.Kind() != slog.KindAny {
+		return appendBinaryValue(enc, b, v)
+	}
+	if x, ok := x.(fmt.Stringer); ok {
+		return enc.appendString(b, x.String())
+	}
+	return enc.appendString(b, fmt.Sprintf("%+v", x))
+}
+
+type msgpackEncoder struct{}
+
+func (msgpackEncoder) appendMap(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xde), uint16(n))
+	}
+	return binary.BigEndian.AppendUint32(append(b, 0xdf), uint32(n))
+}
+
+func (msgpackEncoder) appendArray(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xdc), uint16(n))
+	}
+	return binary.BigEndian.AppendUint32(append(b, 0xdd), uint32(n))
+}
+
+func (msgpackEncoder) appendString(b []byte, s string) []byte {
+	switch n := len(s); {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(n))
+	}
+	return append(b, s...)
+}
+
+func (msgpackEncoder) appendBytes(b []byte, p []byte) []byte {
+	switch n := len(p); {
+	case n <= math.MaxUint8:
+		b = append(b, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xc5), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xc6), uint32(n))
+	}
+	return append(b, p...)
+}
+
+func (e msgpackEncoder) appendInt(b []byte, v int64) []byte {
+	switch {
+	case v >= 0:
+		return e.appendUint(b, uint64(v))
+	case v >= -32:
+		return append(b, byte(int8(v)))
+	case v >= math.MinInt8:
+		return append(b, 0xd0, byte(int8(v)))
+	case v >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(int16(v)))
+	case v >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(int32(v)))
+	}
+	return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(v))
+}
+
+func (msgpackEncoder) appendUint(b []byte, v uint64) []byte {
+	switch {
+	case v < 128:
+		return append(b, byte(v))
+	case v <= math.MaxUint8:
+		return append(b, 0xcc, byte(v))
+	case v <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xcd), uint16(v))
+	case v <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, 0xce), uint32(v))
+	}
+	return binary.BigEndian.AppendUint64(append(b, 0xcf), v)
+}
+
+func (msgpackEncoder) appendFloat(b []byte, v float64) []byte {
+	return binary.BigEndian.AppendUint64(append(b, 0xcb), math.Float64bits(v))
+}
+
+func (msgpackEncoder) appendBool(b []byte, v bool) []byte {
+	if v {
+		return append(b, 0xc3)
+	}
+	return append(b, 0xc2)
+}
+
+func (msgpackEncoder) appendNil(b []byte) []byte {
+	return append(b, 0xc0)
+}
+
+// appendTime appends the timestamp 96 extension type.
+func (msgpackEncoder) appendTime(b []byte, t time.Time) []byte {
+	b = append(b, 0xc7, 12, 0xff)
+	b = binary.BigEndian.AppendUint32(b, uint32(t.Nanosecond()))
+	return binary.BigEndian.AppendUint64(b, uint64(t.Unix()))
+}
+
+type cborEncoder struct{}
+
+func (cborEncoder) appendHead(b []byte, major byte, n uint64) []byte {
+	major <<= 5
+	switch {
+	case n < 24:
+		return append(b, major|byte(n))
+	case n <= math.MaxUint8:
+		return append(b, major|24, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, major|25), uint16(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, major|26), uint32(n))
+	}
+	return binary.BigEndian.AppendUint64(append(b, major|27), n)
+}
+
+func (e cborEncoder) appendMap(b []byte, n int) []byte {
+	return e.appendHead(b, 5, uint64(n))
+}
+
+func (e cborEncoder) appendArray(b []byte, n int) []byte {
+	return e.appendHead(b, 4, uint64(n))
+}
+
+func (e cborEncoder) appendString(b []byte, s string) []byte {
+	return append(e.appendHead(b, 3, uint64(len(s))), s...)
+}
+
+func (e cborEncoder) appendBytes(b []byte, p []byte) []byte {
+	return append(e.appendHead(b, 2, uint64(len(p))), p...)
+}
+
+func (e cborEncoder) appendInt(b []byte, v int64) []byte {
+	if v < 0 {
+		return e.appendHead(b, 1, uint64(^v))
+	}
+	return e.appendHead(b, 0, uint64(v))
+}
+
+func (e cborEncoder) appendUint(b []byte, v uint64) []byte {
+	return e.appendHead(b, 0, v)
+}
+
+func (cborEncoder) appendFloat(b []byte, v float64) []byte {
+	return binary.BigEndian.AppendUint64(append(b, 0xfb), math.Float64bits(v))
+}
+
+func (cborEncoder) appendBool(b []byte, v bool) []byte {
+	if v {
+		return append(b, 0xf5)
+	}
+	return append(b, 0xf4)
+}
+
+func (cborEncoder) appendNil(b []byte) []byte {
+	return append(b, 0xf6)
+}
+
+// appendTime appends the tag 0 standard date/time string.
+func (e cborEncoder) appendTime(b []byte, t time.Time) []byte {
+	return e.appendString(append(b, 0xc0), t.Format(time.RFC3339Nano))
+}
@@ -0,0 +1,316 @@
+package slogx
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// BinaryDecoder reads records written by BinaryHandler from a stream.
+type BinaryDecoder struct {
+	r      *bufio.Reader
+	format BinaryFormat
+}
+
+// NewBinaryDecoder creates BinaryDecoder reading records of the given format from r
+func NewBinaryDecoder(r io.Reader, format BinaryFormat) *BinaryDecoder {
+	return &BinaryDecoder{
+		r:      bufio.NewReader(r),
+		format: format,
+	}
+}
+
+// Decode reads the next record. Groups are returned as nested maps, timestamps
+// as time.Time. It returns io.EOF when there are no more records.
+func (d *BinaryDecoder) Decode() (map[string]any, error) {
+	if _, err := d.r.Peek(1); err != nil {
+		return nil, err
+	}
+	var (
+		v   any
+		err error
+	)
+	if d.format == CBOR {
+		v, err = d.decodeCBOR()
+	} else {
+		v, err = d.decodeMsgpack()
+	}
+	if errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("slogx: record is %T, not a map", v)
+	}
+	return m, nil
+}
+
+func (d *BinaryDecoder) readN(n uint64) ([]byte, error) {
+	if n > math.MaxInt32 {
+		return nil, fmt.Errorf("slogx: length %d is too large", n)
+	}
+	b := make([]byte, n)
+	_, err := io.ReadFull(d.r, b)
+	return b, err
+}
+
+func (d *BinaryDecoder) readUint(size int) (uint64, error) {
+	b, err := d.readN(uint64(size))
+	if err != nil {
+		return 0, err
+	}
+	switch size {
+	case 1:
+		return uint64(b[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	}
+	return binary.BigEndian.Uint64(b), nil
+}
+
+func (d *BinaryDecoder) decodeMap(n uint64, decode func() (any, error)) (any, error) {
+	m := make(map[string]any, min(n, 64))
+	for i := uint64(0); i < n; i++ {
+		k, err := decode()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := k.(string)
+		if !ok {
+			return nil, fmt.Errorf("slogx: map key is %T, not a string", k)
+		}
+		if m[key], err = decode(); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (d *BinaryDecoder) decodeArray(n uint64, decode func() (any, error)) (any, error) {
+	xs := make([]any, 0, min(n, 64))
+	for i := uint64(0); i < n; i++ {
+		x, err := decode()
+		if err != nil {
+			return nil, err
+		}
+		xs = append(xs, x)
+	}
+	return xs, nil
+}
+
+func (d *BinaryDecoder) decodeMsgpack() (any, error) {
+	c, err := d.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xf0 == 0x80:
+		return d.decodeMap(uint64(c&0x0f), d.decodeMsgpack)
+	case c&0xf0 == 0x90:
+		return d.decodeArray(uint64(c&0x0f), d.decodeMsgpack)
+	case c&0xe0 == 0xa0:
+		b, err := d.readN(uint64(c & 0x1f))
+		return string(b), err
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.readUint(1 << (c - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		return d.readN(n)
+	case 0xc7, 0xc8, 0xc9:
+		n, err := d.readUint(1 << (c - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeMsgpackExt(n)
+	case 0xca:
+		n, err := d.readUint(4)
+		return float64(math.Float32frombits(uint32(n))), err
+	case 0xcb:
+		n, err := d.readUint(8)
+		return math.Float64frombits(n), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n, err := d.readUint(1 << (c - 0xcc))
+		if n <= math.MaxInt64 {
+			return int64(n), err
+		}
+		return n, err
+	case 0xd0:
+		n, err := d.readUint(1)
+		return int64(int8(n)), err
+	case 0xd1:
+		n, err := d.readUint(2)
+		return int64(int16(n)), err
+	case 0xd2:
+		n, err := d.readUint(4)
+		return int64(int32(n)), err
+	case 0xd3:
+		n, err := d.readUint(8)
+		return int64(n), err
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return d.decodeMsgpackExt(1 << (c - 0xd4))
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.readUint(1 << (c - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.readN(n)
+		return string(b), err
+	case 0xdc, 0xdd:
+		n, err := d.readUint(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeArray(n, d.decodeMsgpack)
+	case 0xde, 0xdf:
+		n, err := d.readUint(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeMap(n, d.decodeMsgpack)
+	}
+	return nil, fmt.Errorf("slogx: unsupported msgpack type 0x%02x", c)
+}
+
+func (d *BinaryDecoder) decodeMsgpackExt(n uint64) (any, error) {
+	typ, err := d.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	b, err := d.readN(n)
+	if err != nil || int8(typ) != -1 {
+		return b, err
+	}
+	switch n {
+	case 4:
+		return time.Unix(int64(binary.BigEndian.Uint32(b)), 0), nil
+	case 8:
+		x := binary.BigEndian.Uint64(b)
+		return time.Unix(int64(x&0x3ffffffff), int64(x>>34)), nil
+	case 12:
+		return time.Unix(int64(binary.BigEndian.Uint64(b[4:])), int64(binary.BigEndian.Uint32(b))), nil
+	}
+	return nil, fmt.Errorf("slogx: invalid msgpack timestamp length %d", n)
+}
+
+func (d *BinaryDecoder) decodeCBOR() (any, error) {
+	c, err := d.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	major, info := c>>5, c&0x1f
+
+	if major == 7 {
+		switch info {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22, 23:
+			return nil, nil
+		case 25:
+			n, err := d.readUint(2)
+			return float16ToFloat64(uint16(n)), err
+		case 26:
+			n, err := d.readUint(4)
+			return float64(math.Float32frombits(uint32(n))), err
+		case 27:
+			n, err := d.readUint(8)
+			return math.Float64frombits(n), err
+		}
+		return nil, fmt.Errorf("slogx: unsupported cbor simple value %d", info)
+	}
+
+	n := uint64(info)
+	switch {
+	case info >= 24 && info <= 27:
+		if n, err = d.readUint(1 << (info - 24)); err != nil {
+			return nil, err
+		}
+	case info > 27:
+		return nil, fmt.Errorf("slogx: unsupported cbor additional info %d", info)
+	}
+
+	switch major {
+	case 0:
+		if n <= math.MaxInt64 {
+			return int64(n), nil
+		}
+		return n, nil
+	case 1:
+		return ^int64(n), nil
+	case 2:
+		return d.readN(n)
+	case 3:
+		b, err := d.readN(n)
+		return string(b), err
+	case 4:
+		return d.decodeArray(n, d.decodeCBOR)
+	case 5:
+		return d.decodeMap(n, d.decodeCBOR)
+	}
+
+	// major type 6, tagged item
+	x, err := d.decodeCBOR()
+	if err != nil {
+		return nil, err
+	}
+	switch n {
+	case 0:
+		if s, ok := x.(string); ok {
+			return time.Parse(time.RFC3339Nano, s)
+		}
+	case 1:
+		switch x := x.(type) {
+		case int64:
+			return time.Unix(x, 0), nil
+		case float64:
+			sec, frac := math.Modf(x)
+			return time.Unix(int64(sec), int64(frac*1e9)), nil
+		}
+	}
+	return x, nil
+}
+
+func float16ToFloat64(h uint16) float64 {
+	exp, mant := int(h>>10)&0x1f, float64(h&0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 0x1f:
+		if mant == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		return -f
+	}
+	return f
+}
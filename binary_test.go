@@ -0,0 +1,58 @@
+package slogx
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestBinaryHandler(t *testing.T) {
+	tm := time.Date(2024, 3, 1, 12, 30, 0, 123456789, time.UTC)
+	for _, format := range []BinaryFormat{MessagePack, CBOR} {
+		buf := new(bytes.Buffer)
+		logger := slog.New(NewBinaryHandler(buf, format, nil))
+		logger.With("app", "test").WithGroup("req").Info("first", "id", 42, "neg", -1000, "ok", true,
+			slog.Group("user", "name", "bob", "balance", 12.5), "at", tm, "raw", []byte{1, 2})
+		logger.Warn("second")
+
+		dec := NewBinaryDecoder(buf, format)
+		m, err := dec.Decode()
+		if err != nil {
+			t.Fatal(format, err)
+		}
+		if m["msg"] != "first" || m["level"] != "INFO" || m["app"] != "test" {
+			t.Fatalf("%d: unexpected record %v", format, m)
+		}
+		req := m["req"].(map[string]any)
+		if req["id"] != int64(42) || req["neg"] != int64(-1000) || req["ok"] != true {
+			t.Fatalf("%d: unexpected group %v", format, req)
+		}
+		if user := req["user"].(map[string]any); user["name"] != "bob" || user["balance"] != 12.5 {
+			t.Fatalf("%d: unexpected nested group %v", format, user)
+		}
+		if at := req["at"].(time.Time); !at.Equal(tm) {
+			t.Fatalf("%d: time %v, expected %v", format, at, tm)
+		}
+		if !bytes.Equal(req["raw"].([]byte), []byte{1, 2}) {
+			t.Fatalf("%d: unexpected bytes %v", format, req["raw"])
+		}
+
+		if m, err = dec.Decode(); err != nil || m["msg"] != "second" || m["level"] != "WARN" {
+			t.Fatal(format, m, err)
+		}
+		if _, err = dec.Decode(); !errors.Is(err, io.EOF) {
+			t.Fatal(format, err)
+		}
+	}
+}
+
+func BenchmarkBinaryHandler(b *testing.B) {
+	b.ReportAllocs()
+	logger := slog.New(NewBinaryHandler(io.Discard, MessagePack, nil))
+	for i := 0; i < b.N; i++ {
+		logger.Error("this is an error", "number", 12, "string", "data")
+	}
+}
@@ -0,0 +1,28 @@
+package slogx
+
+import "time"
+
+// Clock is the time source of handlers. Tests substitute it to freeze time.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc adapts a function to Clock.
+type ClockFunc func() time.Time
+
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// systemClock returns Clock returning the current local time.
+func systemClock() Clock {
+	return ClockFunc(time.Now)
+}
+
+// clockOrSystem returns c, or the system clock if c is nil.
+func clockOrSystem(c Clock) Clock {
+	if c == nil {
+		return systemClock()
+	}
+	return c
+}
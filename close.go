@@ -0,0 +1,70 @@
+package slogx
+
+import (
+	"errors"
+	"log/slog"
+	"reflect"
+)
+
+// Close flushes and closes h and every handler it wraps.
+// Wrappers expose the handlers they delegate to with an Unwrap() slog.Handler
+// or Unwrap() []slog.Handler method; each handler in the chain implementing
+// Flush() error and/or Close() error gets them called, in that order.
+// A handler reached several times, e.g. through two wrappers or as copies
+// made by WithAttrs and WithGroup sharing one sink, is closed once.
+// Close returns all the errors encountered, joined.
+//
+//	defer slogx.Close(logger.Handler())
+func Close(h slog.Handler) error {
+	var errs []error
+	seen := make(map[any]bool)
+	walkHandlers(h, func(h slog.Handler) {
+		if key, ok := handlerKey(h); ok {
+			if seen[key] {
+				return
+			}
+			seen[key] = true
+		}
+		if f, ok := h.(interface{ Flush() error }); ok {
+			errs = append(errs, f.Flush())
+		}
+		if c, ok := h.(interface{ Close() error }); ok {
+			errs = append(errs, c.Close())
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// walkHandlers calls f for h and all the handlers it wraps, outer first.
+func walkHandlers(h slog.Handler, f func(slog.Handler)) {
+	if h == nil {
+		return
+	}
+	f(h)
+	switch u := h.(type) {
+	case interface{ Unwrap() slog.Handler }:
+		walkHandlers(u.Unwrap(), f)
+	case interface{ Unwrap() []slog.Handler }:
+		for _, h := range u.Unwrap() {
+			walkHandlers(h, f)
+		}
+	}
+}
+
+// sharedHandler is implemented by the handlers whose copies made by WithAttrs
+// and WithGroup share the state identified by sharedState.
+type sharedHandler interface {
+	sharedState() any
+}
+
+// handlerKey returns the identity of the sink of h, false if h can't be
+// deduplicated, as the handlers of value types, possibly not comparable.
+func handlerKey(h slog.Handler) (any, bool) {
+	if s, ok := h.(sharedHandler); ok {
+		return s.sharedState(), true
+	}
+	if reflect.ValueOf(h).Kind() == reflect.Pointer {
+		return h, true
+	}
+	return nil, false
+}
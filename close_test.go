@@ -0,0 +1,98 @@
+package slogx
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+)
+
+// closeRecorder records the Flush and Close calls of a handler chain.
+type closeRecorder struct {
+	slog.Handler
+	name  string
+	calls *[]string
+	err   error
+}
+
+func (h closeRecorder) Flush() error {
+	*h.calls = append(*h.calls, h.name+".Flush")
+	return nil
+}
+
+func (h closeRecorder) Close() error {
+	*h.calls = append(*h.calls, h.name+".Close")
+	return h.err
+}
+
+type closeWrapper struct {
+	closeRecorder
+	inner slog.Handler
+}
+
+func (h closeWrapper) Unwrap() slog.Handler {
+	return h.inner
+}
+
+type closeFanout struct {
+	slog.Handler
+	children []slog.Handler
+}
+
+func (h closeFanout) Unwrap() []slog.Handler {
+	return h.children
+}
+
+func TestClose(t *testing.T) {
+	var calls []string
+	discard := slog.NewJSONHandler(io.Discard, nil)
+	recorder := func(name string, err error) closeRecorder {
+		return closeRecorder{Handler: discard, name: name, calls: &calls, err: err}
+	}
+	errA, errC := errors.New("a"), errors.New("c")
+	h := closeWrapper{recorder("outer", nil), closeFanout{discard, []slog.Handler{
+		recorder("a", errA),
+		closeWrapper{recorder("b", nil), recorder("c", errC)},
+		nil,
+	}}}
+
+	err := Close(h)
+	if !errors.Is(err, errA) || !errors.Is(err, errC) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	expected := []string{"outer.Flush", "outer.Close", "a.Flush", "a.Close", "b.Flush", "b.Close", "c.Flush", "c.Close"}
+	if !slices.Equal(calls, expected) {
+		t.Fatalf("\n%v\nexpected\n%v", calls, expected)
+	}
+}
+
+func TestCloseSharedSink(t *testing.T) {
+	var calls []string
+	discard := slog.NewJSONHandler(io.Discard, nil)
+	sink := &closeRecorder{Handler: discard, name: "sink", calls: &calls}
+	h := closeFanout{discard, []slog.Handler{
+		closeWrapper{closeRecorder{Handler: discard, name: "a", calls: &calls}, sink},
+		closeWrapper{closeRecorder{Handler: discard, name: "b", calls: &calls}, sink},
+	}}
+
+	if err := Close(h); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"a.Flush", "a.Close", "sink.Flush", "sink.Close", "b.Flush", "b.Close"}
+	if !slices.Equal(calls, expected) {
+		t.Fatalf("\n%v\nexpected\n%v", calls, expected)
+	}
+}
+
+func TestCloseSocketFallback(t *testing.T) {
+	var calls []string
+	fallback := closeRecorder{Handler: slog.NewJSONHandler(io.Discard, nil), name: "fallback", calls: &calls}
+	h := NewSocketHandler("tcp", "127.0.0.1:1", &SocketOptions{Fallback: fallback})
+	if err := Close(h); err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 0 {
+		t.Fatalf("caller-owned fallback closed: %v", calls)
+	}
+}
@@ -0,0 +1,220 @@
+package slogx
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// CloudWatch Logs PutLogEvents limits.
+const (
+	cloudWatchMaxEvents     = 10000
+	cloudWatchMaxBatchBytes = 1048576
+	cloudWatchEventOverhead = 26
+	cloudWatchMaxEventBytes = 262144 - cloudWatchEventOverhead
+	cloudWatchMaxBatchSpan  = 24 * time.Hour
+)
+
+type (
+	// CloudWatchEvent is a log event of PutLogEvents.
+	CloudWatchEvent struct {
+		Timestamp time.Time
+		Message   string
+	}
+
+	// CloudWatchClient is the part of the CloudWatch Logs API used by
+	// CloudWatchHandler, usually a thin adapter over the AWS SDK client.
+	// The adapter maps ResourceNotFoundException to ErrCloudWatchNotFound,
+	// InvalidSequenceTokenException to *CloudWatchSequenceTokenError and
+	// ignores ResourceAlreadyExistsException.
+	CloudWatchClient interface {
+		CreateLogGroup(ctx context.Context, group string) error
+		CreateLogStream(ctx context.Context, group, stream string) error
+		PutLogEvents(ctx context.Context, group, stream string, events []CloudWatchEvent, sequenceToken string) (nextSequenceToken string, err error)
+	}
+
+	// CloudWatchSequenceTokenError is returned by CloudWatchClient.PutLogEvents
+	// when the sequence token is not the expected one.
+	CloudWatchSequenceTokenError struct {
+		Expected string
+	}
+
+	// CloudWatchOptions configures CloudWatchHandler. Zero values select the defaults.
+	CloudWatchOptions struct {
+		slog.HandlerOptions
+
+		Group  string
+		Stream string
+
+		FlushInterval time.Duration // 5s by default
+		BufferSize    int           // bytes of records kept while sending is slow or failing, 8MiB by default
+		Clock         Clock         // the system clock by default
+	}
+
+	// CloudWatchHandler sends records encoded as JSON to a CloudWatch Logs
+	// stream, for services shipping logs without an agent.
+	// Records are batched in PutLogEvents calls within the API size, count
+	// and time span limits, from a background goroutine. The log group and
+	// stream are created when missing. Records exceeding the 256KiB event
+	// limit are dropped and counted. Close sends the pending records and
+	// must be called before the process exits.
+	CloudWatchHandler struct {
+		opts slog.HandlerOptions
+		enc  RecordEncoder
+		w    *cloudWatchWriter
+	}
+
+	cloudWatchWriter struct {
+		client        CloudWatchClient
+		group, stream string
+
+		sequenceToken string // guarded by batcher.sendMu
+		*batcher
+	}
+)
+
+var (
+	_ slog.Handler  = (*CloudWatchHandler)(nil)
+	_ StatsProvider = (*CloudWatchHandler)(nil)
+)
+
+// ErrCloudWatchNotFound is returned by CloudWatchClient when the log group or stream does not exist.
+var ErrCloudWatchNotFound = errors.New("cloudwatch: log group or stream not found")
+
+func (e *CloudWatchSequenceTokenError) Error() string {
+	return "cloudwatch: invalid sequence token, expected " + e.Expected
+}
+
+// NewCloudWatchHandler creates CloudWatchHandler sending records with the client
+func NewCloudWatchHandler(client CloudWatchClient, opts CloudWatchOptions) *CloudWatchHandler {
+	w := &cloudWatchWriter{
+		client: client,
+		group:  opts.Group,
+		stream: opts.Stream,
+	}
+	w.batcher = newBatcher(batchOptions{
+		maxEntries:    cloudWatchMaxEvents,
+		maxBytes:      cloudWatchMaxBatchBytes,
+		entryOverhead: cloudWatchEventOverhead,
+		maxBuffered:   opts.BufferSize,
+		interval:      opts.FlushInterval,
+		clock:         opts.Clock,
+	}, w.putEntries)
+	return &CloudWatchHandler{
+		opts: opts.HandlerOptions,
+		enc:  NewRecordEncoder(&opts.HandlerOptions),
+		w:    w,
+	}
+}
+
+func (h *CloudWatchHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return levelEnabled(&h.opts, level)
+}
+
+func (h *CloudWatchHandler) Handle(_ context.Context, r slog.Record) error {
+	t := r.Time
+	if t.IsZero() {
+		t = h.w.opts.clock.Now()
+	}
+
+	buf := bufPool.Get().(*[]byte)
+	b := h.enc.AppendJSON((*buf)[:0], r)
+	if len(b) > cloudWatchMaxEventBytes {
+		h.w.dropped.Add(1)
+	} else {
+		h.w.add(t, b)
+	}
+
+	if cap(b) <= 64<<10 {
+		*buf = b
+		bufPool.Put(buf)
+	}
+	return nil
+}
+
+func (h *CloudWatchHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.enc = h.enc.WithAttrs(attrs)
+	return &h2
+}
+
+func (h *CloudWatchHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.enc = h.enc.WithGroup(name)
+	return &h2
+}
+
+// Flush sends the pending records.
+func (h *CloudWatchHandler) Flush() error {
+	return h.w.Flush()
+}
+
+// Close sends the pending records and stops the background goroutine.
+func (h *CloudWatchHandler) Close() error {
+	return h.w.Close()
+}
+
+// Stats returns the delivery counters of the handler.
+func (h *CloudWatchHandler) Stats() Stats {
+	return h.w.Stats()
+}
+
+func (h *CloudWatchHandler) sharedState() any {
+	return h.w
+}
+
+// putEntries puts the entries in chronological order and in calls spanning at most 24 hours.
+func (w *cloudWatchWriter) putEntries(ctx context.Context, entries []batchEntry) error {
+	entries = slices.Clone(entries)
+	slices.SortStableFunc(entries, func(a, b batchEntry) int {
+		return a.time.Compare(b.time)
+	})
+	for len(entries) > 0 {
+		n := 1
+		for n < len(entries) && entries[n].time.Sub(entries[0].time) <= cloudWatchMaxBatchSpan {
+			n++
+		}
+		events := make([]CloudWatchEvent, n)
+		for i, e := range entries[:n] {
+			events[i] = CloudWatchEvent{Timestamp: e.time, Message: string(e.data)}
+		}
+		if err := w.put(ctx, events); err != nil {
+			return err
+		}
+		entries = entries[n:]
+	}
+	return nil
+}
+
+func (w *cloudWatchWriter) put(ctx context.Context, events []CloudWatchEvent) error {
+	created := false
+	for {
+		token, err := w.client.PutLogEvents(ctx, w.group, w.stream, events, w.sequenceToken)
+		if err == nil {
+			w.sequenceToken = token
+			return nil
+		}
+		var tokenErr *CloudWatchSequenceTokenError
+		switch {
+		case errors.As(err, &tokenErr) && tokenErr.Expected != w.sequenceToken:
+			w.sequenceToken = tokenErr.Expected
+		case errors.Is(err, ErrCloudWatchNotFound) && !created:
+			created = true
+			if err := w.create(ctx); err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+	}
+}
+
+func (w *cloudWatchWriter) create(ctx context.Context) error {
+	if err := w.client.CreateLogGroup(ctx, w.group); err != nil {
+		return err
+	}
+	w.sequenceToken = ""
+	return w.client.CreateLogStream(ctx, w.group, w.stream)
+}
@@ -0,0 +1,90 @@
+package slogx
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeCloudWatch struct {
+	created bool
+	token   string
+	puts    [][]CloudWatchEvent
+}
+
+func (c *fakeCloudWatch) CreateLogGroup(context.Context, string) error {
+	return nil
+}
+
+func (c *fakeCloudWatch) CreateLogStream(context.Context, string, string) error {
+	c.created = true
+	c.token = ""
+	return nil
+}
+
+func (c *fakeCloudWatch) PutLogEvents(_ context.Context, _, _ string, events []CloudWatchEvent, token string) (string, error) {
+	if !c.created {
+		return "", ErrCloudWatchNotFound
+	}
+	if token != c.token {
+		return "", &CloudWatchSequenceTokenError{Expected: c.token}
+	}
+	c.puts = append(c.puts, events)
+	c.token += "x"
+	return c.token, nil
+}
+
+func TestCloudWatchHandler(t *testing.T) {
+	client := new(fakeCloudWatch)
+	h := NewCloudWatchHandler(client, CloudWatchOptions{
+		Group:         "group",
+		Stream:        "stream",
+		FlushInterval: time.Hour,
+	})
+	logger := slog.New(h)
+	logger.Info("first", "n", 1)
+	logger.Info("second", "n", 2)
+	if err := h.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	client.token = "changed"
+	logger.Info("third", "n", 3)
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(client.puts) != 2 || len(client.puts[0]) != 2 || len(client.puts[1]) != 1 {
+		t.Fatalf("unexpected puts %v", client.puts)
+	}
+	if m := client.puts[0][1].Message; !strings.Contains(m, `"msg":"second","n":2`) || strings.HasSuffix(m, "\n") {
+		t.Fatalf("unexpected message %q", m)
+	}
+	if s := h.Stats(); s.Handled != 3 || s.Dropped != 0 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+}
+
+func TestCloudWatchHandlerOversized(t *testing.T) {
+	client := new(fakeCloudWatch)
+	h := NewCloudWatchHandler(client, CloudWatchOptions{
+		Group:         "group",
+		Stream:        "stream",
+		FlushInterval: time.Hour,
+	})
+	logger := slog.New(h)
+	logger.Info("big", "data", strings.Repeat("я", cloudWatchMaxEventBytes/2))
+	logger.Info("small")
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(client.puts) != 1 || len(client.puts[0]) != 1 || !strings.Contains(client.puts[0][0].Message, `"msg":"small"`) {
+		t.Fatalf("unexpected puts %v", client.puts)
+	}
+	if s := h.Stats(); s.Handled != 1 || s.Dropped != 1 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+}
@@ -0,0 +1,159 @@
+// Command slogx-pretty renders slog JSON or logfmt lines read from stdin or
+// files with the colorized output of the pretty handler:
+//
+//	kubectl logs my-pod | slogx-pretty --level warn --filter user.id=42 --since 10m
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fpawel/slogx/pretty"
+)
+
+type filter struct {
+	key, value string
+}
+
+func main() {
+	var (
+		level   = flag.String("level", "debug", "minimum level of the records to print")
+		since   = flag.String("since", "", "print records newer than a duration (10m) or an RFC3339 time")
+		layout  = flag.String("time", "15:04:05", "time layout, empty to hide the time")
+		filters []filter
+	)
+	flag.Func("filter", "print records with the attribute key=value, group keys joined by dots; repeatable", func(s string) error {
+		k, v, ok := strings.Cut(s, "=")
+		if !ok {
+			return errors.New("expected key=value")
+		}
+		filters = append(filters, filter{k, v})
+		return nil
+	})
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] [file ...]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	var minLevel slog.Level
+	if err := minLevel.UnmarshalText([]byte(*level)); err != nil {
+		fatal(err)
+	}
+	sinceTime, err := parseSince(*since)
+	if err != nil {
+		fatal(err)
+	}
+
+	h := pretty.NewHandler().
+		WithOutput(os.Stdout).
+		WithTimeLayout(*layout).
+		WithLevel(minLevel)
+	p := printer{
+		handler: h,
+		since:   sinceTime,
+		filters: filters,
+	}
+
+	if flag.NArg() == 0 {
+		err = p.print(os.Stdin)
+	}
+	for _, name := range flag.Args() {
+		if err = p.printFile(name); err != nil {
+			break
+		}
+	}
+	if err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "slogx-pretty:", err)
+	os.Exit(1)
+}
+
+func parseSince(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid since %q: expected a duration or an RFC3339 time", s)
+	}
+	return t, nil
+}
+
+type printer struct {
+	handler pretty.Handler
+	since   time.Time
+	filters []filter
+}
+
+func (p printer) printFile(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return p.print(f)
+}
+
+func (p printer) print(r io.Reader) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64<<10), 16<<20)
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		e, err := parseLine(line)
+		if err != nil {
+			// not a structured record, print it as is unless records are filtered
+			if len(p.filters) == 0 && p.since.IsZero() {
+				fmt.Println(line)
+			}
+			continue
+		}
+		if p.match(e) {
+			if err := p.handle(e); err != nil {
+				return err
+			}
+		}
+	}
+	return sc.Err()
+}
+
+func (p printer) match(e entry) bool {
+	if !p.since.IsZero() && !e.time.IsZero() && e.time.Before(p.since) {
+		return false
+	}
+	for _, f := range p.filters {
+		v, ok := e.lookup(f.key)
+		if !ok || v != f.value {
+			return false
+		}
+	}
+	return true
+}
+
+func (p printer) handle(e entry) error {
+	ctx := context.Background()
+	if !p.handler.Enabled(ctx, e.level) {
+		return nil
+	}
+	r := slog.NewRecord(e.time, e.level, e.msg, 0)
+	r.AddAttrs(e.attrs...)
+	return p.handler.Handle(ctx, r)
+}
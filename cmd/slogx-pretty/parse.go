@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type entry struct {
+	time  time.Time
+	level slog.Level
+	msg   string
+	attrs []slog.Attr
+}
+
+var errNotRecord = errors.New("not a log record")
+
+// parseLine parses a slog JSON or logfmt line.
+func parseLine(line string) (entry, error) {
+	var (
+		attrs []slog.Attr
+		err   error
+	)
+	if strings.HasPrefix(strings.TrimSpace(line), "{") {
+		attrs, err = parseJSON(line)
+	} else {
+		attrs, err = parseLogfmt(line)
+	}
+	if err != nil {
+		return entry{}, err
+	}
+
+	var e entry
+	found := false
+	for _, a := range attrs {
+		switch a.Key {
+		case slog.TimeKey:
+			if t, err := time.Parse(time.RFC3339Nano, a.Value.String()); err == nil {
+				e.time = t
+				continue
+			}
+		case slog.LevelKey:
+			if e.level.UnmarshalText([]byte(a.Value.String())) == nil {
+				found = true
+				continue
+			}
+		case slog.MessageKey:
+			e.msg = a.Value.String()
+			found = true
+			continue
+		}
+		e.attrs = append(e.attrs, a)
+	}
+	if !found {
+		return entry{}, errNotRecord
+	}
+	return e, nil
+}
+
+// lookup returns the string form of the attribute with the dotted key.
+func (e entry) lookup(key string) (string, bool) {
+	attrs := e.attrs
+	for {
+		var next []slog.Attr
+		for _, a := range attrs {
+			if a.Key == key {
+				return a.Value.String(), true
+			}
+			if a.Value.Kind() == slog.KindGroup && strings.HasPrefix(key, a.Key+".") {
+				next = a.Value.Group()
+				key = key[len(a.Key)+1:]
+				break
+			}
+		}
+		if next == nil {
+			return "", false
+		}
+		attrs = next
+	}
+}
+
+// parseJSON parses a JSON object keeping the order of its keys.
+// Nested objects become groups.
+func parseJSON(line string) ([]slog.Attr, error) {
+	dec := json.NewDecoder(strings.NewReader(line))
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, errNotRecord
+	}
+	return decodeObject(dec)
+}
+
+func decodeObject(dec *json.Decoder) ([]slog.Attr, error) {
+	var attrs []slog.Attr
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected %v", tok)
+		}
+		v, err := decodeValue(dec)
+		if err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, slog.Attr{Key: key, Value: v})
+	}
+	_, err := dec.Token()
+	return attrs, err
+}
+
+func decodeValue(dec *json.Decoder) (slog.Value, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return slog.Value{}, err
+	}
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok == '{' {
+			attrs, err := decodeObject(dec)
+			return slog.GroupValue(attrs...), err
+		}
+		var xs []any
+		for dec.More() {
+			var x any
+			if err := dec.Decode(&x); err != nil {
+				return slog.Value{}, err
+			}
+			xs = append(xs, x)
+		}
+		_, err := dec.Token()
+		return slog.AnyValue(xs), err
+	case json.Number:
+		if n, err := tok.Int64(); err == nil {
+			return slog.Int64Value(n), nil
+		}
+		f, err := tok.Float64()
+		return slog.Float64Value(f), err
+	case string:
+		return slog.StringValue(tok), nil
+	case bool:
+		return slog.BoolValue(tok), nil
+	}
+	return slog.AnyValue(nil), nil
+}
+
+// parseLogfmt parses key=value pairs with optionally quoted values.
+func parseLogfmt(line string) ([]slog.Attr, error) {
+	var attrs []slog.Attr
+	line = strings.TrimSpace(line)
+	for line != "" {
+		eq := strings.IndexByte(line, '=')
+		if eq <= 0 || strings.ContainsAny(line[:eq], " \t\"") {
+			return nil, errNotRecord
+		}
+		key := line[:eq]
+		line = line[eq+1:]
+
+		var val string
+		if strings.HasPrefix(line, `"`) {
+			q, err := strconv.QuotedPrefix(line)
+			if err != nil {
+				return nil, errNotRecord
+			}
+			line = line[len(q):]
+			val, _ = strconv.Unquote(q)
+		} else {
+			end := strings.IndexAny(line, " \t")
+			if end < 0 {
+				end = len(line)
+			}
+			val, line = line[:end], line[end:]
+		}
+		line = strings.TrimLeft(line, " \t")
+		attrs = append(attrs, slog.String(key, val))
+	}
+	return attrs, nil
+}
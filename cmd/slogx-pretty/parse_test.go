@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fpawel/slogx/pretty"
+)
+
+func TestParseLineJSON(t *testing.T) {
+	e, err := parseLine(`{"time":"2024-03-01T12:30:00.5Z","level":"WARN","msg":"slow","ms":120,"ratio":0.5,"ok":true,"user":{"id":42,"name":"bob"},"tags":["a","b"],"none":null}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !e.time.Equal(time.Date(2024, 3, 1, 12, 30, 0, 5e8, time.UTC)) || e.level != slog.LevelWarn || e.msg != "slow" {
+		t.Fatalf("unexpected entry %+v", e)
+	}
+	expected := "[ms=120 ratio=0.5 ok=true user=[id=42 name=bob] tags=[a b] none=<nil>]"
+	if s := fmt.Sprint(e.attrs); s != expected {
+		t.Fatalf("\n%s\nexpected\n%s", s, expected)
+	}
+	if v, ok := e.lookup("user.id"); !ok || v != "42" {
+		t.Fatalf("lookup user.id: %q %v", v, ok)
+	}
+	if _, ok := e.lookup("user.email"); ok {
+		t.Fatal("lookup user.email: found")
+	}
+}
+
+func TestParseLineLogfmt(t *testing.T) {
+	e, err := parseLine(`time=2024-03-01T12:30:00Z level=ERROR msg="request failed" err="dial: \"refused\"" path=/api  n=3`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.time.IsZero() || e.level != slog.LevelError || e.msg != "request failed" {
+		t.Fatalf("unexpected entry %+v", e)
+	}
+	expected := `[err=dial: "refused" path=/api n=3]`
+	if s := fmt.Sprint(e.attrs); s != expected {
+		t.Fatalf("\n%s\nexpected\n%s", s, expected)
+	}
+}
+
+func TestParseLineNotRecord(t *testing.T) {
+	for _, line := range []string{
+		"panic: runtime error",
+		`{"a":1}`,
+		`{"msg":`,
+		"key=value",
+		`msg="unterminated`,
+		"[1,2]",
+	} {
+		if _, err := parseLine(line); err == nil {
+			t.Errorf("%q: parsed", line)
+		} else if !strings.HasPrefix(line, `{"msg"`) && !errors.Is(err, errNotRecord) {
+			t.Errorf("%q: unexpected error %v", line, err)
+		}
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	if tm, err := parseSince(""); err != nil || !tm.IsZero() {
+		t.Fatalf("empty: %v %v", tm, err)
+	}
+	if tm, err := parseSince("10m"); err != nil || time.Since(tm) < 10*time.Minute || time.Since(tm) > 11*time.Minute {
+		t.Fatalf("duration: %v %v", tm, err)
+	}
+	if tm, err := parseSince("2024-03-01T12:00:00Z"); err != nil || !tm.Equal(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Fatalf("time: %v %v", tm, err)
+	}
+	if _, err := parseSince("yesterday"); err == nil {
+		t.Fatal("invalid: parsed")
+	}
+}
+
+func TestPrinter(t *testing.T) {
+	var buf bytes.Buffer
+	p := printer{
+		handler: pretty.NewHandler().WithOutput(&buf).WithColor(false).WithTimeLayout(""),
+		since:   time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		filters: []filter{{"user.id", "42"}},
+	}
+	input := `{"time":"2024-03-01T12:30:00Z","level":"INFO","msg":"match","user":{"id":42}}
+{"time":"2024-03-01T11:30:00Z","level":"INFO","msg":"old","user":{"id":42}}
+{"time":"2024-03-01T12:30:00Z","level":"INFO","msg":"other user","user":{"id":7}}
+not a record
+
+level=WARN msg="no time" user.id=42
+`
+	if err := p.print(strings.NewReader(input)); err != nil {
+		t.Fatal(err)
+	}
+	expected := "INFO  match {\"user\":{\"id\":42}}\nWARN  no time {\"user.id\":\"42\"}\n"
+	if buf.String() != expected {
+		t.Fatalf("\n%s\nexpected\n%s", buf.String(), expected)
+	}
+}
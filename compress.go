@@ -0,0 +1,154 @@
+package slogx
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// Compressor creates the compressing writer of a log segment.
+// Ext is appended to the segment file name.
+type Compressor struct {
+	Ext       string
+	NewWriter func(w io.Writer) (io.WriteCloser, error)
+}
+
+// Gzip is Compressor producing gzip segments.
+// A zstd Compressor can be built the same way around a zstd encoder, e.g.
+//
+//	slogx.Compressor{Ext: ".zst", NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+//		return zstd.NewWriter(w)
+//	}}
+var Gzip = Compressor{
+	Ext: ".gz",
+	NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+		return gzip.NewWriter(w), nil
+	},
+}
+
+// CompressedFileWriter is io.Writer streaming log records into compressed
+// segment files named <path>.<n><ext>. A new segment is started once the
+// current one has received MaxSize uncompressed bytes; a single Write is never
+// split across segments, so every segment holds whole records.
+// It is meant to be the output of a slog handler:
+//
+//	w := slogx.NewCompressedFileWriter("batch.log", 64<<20, slogx.Gzip)
+//	defer w.Close()
+//	logger := slog.New(slog.NewJSONHandler(w, nil))
+type CompressedFileWriter struct {
+	Path       string
+	MaxSize    int64 // zero means no rollover
+	Compressor Compressor
+
+	mu      sync.Mutex
+	file    *os.File
+	zw      io.WriteCloser
+	written int64
+	seq     int
+}
+
+var _ io.WriteCloser = (*CompressedFileWriter)(nil)
+
+// NewCompressedFileWriter creates CompressedFileWriter.
+// Segment files are created lazily on the first Write.
+func NewCompressedFileWriter(path string, maxSize int64, c Compressor) *CompressedFileWriter {
+	return &CompressedFileWriter{
+		Path:       path,
+		MaxSize:    maxSize,
+		Compressor: c,
+	}
+}
+
+func (w *CompressedFileWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.zw == nil {
+		if err := w.openSegment(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.zw.Write(p)
+	w.written += int64(n)
+	if err != nil {
+		return n, err
+	}
+	if w.MaxSize > 0 && w.written >= w.MaxSize {
+		err = w.closeSegment()
+	}
+	return n, err
+}
+
+// Flush writes buffered compressed data of the current segment to the file.
+func (w *CompressedFileWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.zw == nil {
+		return nil
+	}
+	if f, ok := w.zw.(interface{ Flush() error }); ok {
+		if err := f.Flush(); err != nil {
+			return err
+		}
+	}
+	return w.file.Sync()
+}
+
+// Close finishes the current segment. Subsequent writes start a new segment.
+func (w *CompressedFileWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.closeSegment()
+}
+
+// Segment returns the name of the segment file currently written, if any.
+func (w *CompressedFileWriter) Segment() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return ""
+	}
+	return w.file.Name()
+}
+
+func (w *CompressedFileWriter) openSegment() error {
+	for {
+		w.seq++
+		name := fmt.Sprintf("%s.%04d%s", w.Path, w.seq, w.Compressor.Ext)
+		f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		newWriter := w.Compressor.NewWriter
+		if newWriter == nil {
+			newWriter = Gzip.NewWriter
+		}
+		zw, err := newWriter(f)
+		if err != nil {
+			return errors.Join(err, f.Close(), os.Remove(name))
+		}
+		w.file, w.zw, w.written = f, zw, 0
+		return nil
+	}
+}
+
+func (w *CompressedFileWriter) closeSegment() error {
+	if w.zw == nil {
+		return nil
+	}
+	err := w.zw.Close()
+	if err == nil {
+		err = w.file.Sync()
+	}
+	err = errors.Join(err, w.file.Close())
+	w.file, w.zw = nil, nil
+	return err
+}
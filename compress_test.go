@@ -0,0 +1,88 @@
+package slogx
+
+import (
+	"compress/gzip"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readGzip(t *testing.T, name string) string {
+	t.Helper()
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestCompressedFileWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "batch.log")
+	w := NewCompressedFileWriter(path, 40, Gzip)
+	if w.Segment() != "" {
+		t.Fatalf("segment created before the first write: %s", w.Segment())
+	}
+	logger := slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	logger.Info("first", "n", 1)
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if w.Segment() != path+".0001.gz" {
+		t.Fatalf("unexpected segment %s", w.Segment())
+	}
+	logger.Info("second", "n", 2) // exceeds MaxSize, the segment is finished
+	logger.Info("third", "n", 3)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	segments, _ := filepath.Glob(path + ".*")
+	if len(segments) != 2 {
+		t.Fatalf("unexpected segments %v", segments)
+	}
+	expected := []string{
+		"level=INFO msg=first n=1\nlevel=INFO msg=second n=2\n",
+		"level=INFO msg=third n=3\n",
+	}
+	for i, name := range segments {
+		if s := readGzip(t, name); s != expected[i] {
+			t.Fatalf("%s:\n%s\nexpected\n%s", name, s, expected[i])
+		}
+	}
+}
+
+func TestCompressedFileWriterExisting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "batch.log")
+	if err := os.WriteFile(path+".0001.gz", nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	w := NewCompressedFileWriter(path, 0, Compressor{Ext: ".gz"})
+	if _, err := io.WriteString(w, "line\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if s := readGzip(t, path+".0002.gz"); !strings.HasPrefix(s, "line") {
+		t.Fatalf("unexpected segment %q", s)
+	}
+}
@@ -0,0 +1,282 @@
+package slogx
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Datadog HTTP logs intake limits.
+const (
+	datadogMaxEntries    = 1000
+	datadogMaxBatchBytes = 5 << 20
+	datadogMaxEntryBytes = 1 << 20
+)
+
+// DatadogIntakeURL is the default Datadog logs intake endpoint, US1 site.
+const DatadogIntakeURL = "https://http-intake.logs.datadoghq.com/api/v2/logs"
+
+type (
+	// DatadogOptions configures DatadogHandler. Zero values select the defaults.
+	DatadogOptions struct {
+		slog.HandlerOptions
+
+		APIKey string
+		URL    string // DatadogIntakeURL by default
+
+		Service  string
+		Source   string // the ddsource field
+		Hostname string
+		Tags     []string // "key:value" tags of all the records
+
+		// TagKeys are the top-level record attributes also sent as tags,
+		// e.g. "env" or "version"
+		TagKeys []string
+
+		// SpanContext returns the trace of the context, e.g. of the active
+		// OpenTelemetry or ddtrace span, to correlate records with traces.
+		// OpenTelemetry 128-bit hex IDs are converted to the Datadog 64-bit form.
+		SpanContext func(ctx context.Context) (traceID, spanID string)
+
+		Client        *http.Client  // http.DefaultClient by default
+		FlushInterval time.Duration // 5s by default
+		BufferSize    int           // bytes of records kept while sending is slow or failing, 40MiB by default
+		Clock         Clock         // the system clock by default
+	}
+
+	// DatadogHandler sends records to the Datadog HTTP logs intake in
+	// gzip-compressed batches from a background goroutine, retrying failed
+	// requests. Close sends the pending records and must be called before
+	// the process exits.
+	DatadogHandler struct {
+		opts DatadogOptions
+		enc  RecordEncoder
+		w    *datadogWriter
+	}
+
+	datadogWriter struct {
+		url, apiKey string
+		client      *http.Client
+		*batcher
+	}
+)
+
+var (
+	_ slog.Handler  = (*DatadogHandler)(nil)
+	_ StatsProvider = (*DatadogHandler)(nil)
+)
+
+// NewDatadogHandler creates DatadogHandler
+func NewDatadogHandler(opts DatadogOptions) *DatadogHandler {
+	w := &datadogWriter{
+		url:    opts.URL,
+		apiKey: opts.APIKey,
+		client: opts.Client,
+	}
+	if w.url == "" {
+		w.url = DatadogIntakeURL
+	}
+	if w.client == nil {
+		w.client = http.DefaultClient
+	}
+	w.batcher = newBatcher(batchOptions{
+		maxEntries:    datadogMaxEntries,
+		maxBytes:      datadogMaxBatchBytes,
+		entryOverhead: 1,
+		maxBuffered:   opts.BufferSize,
+		interval:      opts.FlushInterval,
+		clock:         opts.Clock,
+	}, w.post)
+	h := &DatadogHandler{
+		opts: opts,
+		enc:  NewRecordEncoder(&opts.HandlerOptions),
+		w:    w,
+	}
+	h.enc.Rename = datadogRenameBuiltin
+	return h
+}
+
+func (h *DatadogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return levelEnabled(&h.opts.HandlerOptions, level)
+}
+
+func (h *DatadogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.enc = h.enc.WithAttrs(attrs)
+	return &h2
+}
+
+func (h *DatadogHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.enc = h.enc.WithGroup(name)
+	return &h2
+}
+
+func (h *DatadogHandler) Handle(ctx context.Context, r slog.Record) error {
+	tags := h.opts.Tags
+	service := h.opts.Service
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Key == "service" {
+			service = a.Value.Resolve().String()
+		}
+		for _, k := range h.opts.TagKeys {
+			if a.Key == k {
+				tags = append(tags[:len(tags):len(tags)], k+":"+a.Value.Resolve().String())
+			}
+		}
+		return true
+	})
+
+	var top []slog.Attr
+	if h.opts.Source != "" {
+		top = append(top, slog.String("ddsource", h.opts.Source))
+	}
+	if service != "" {
+		top = append(top, slog.String("service", service))
+	}
+	if h.opts.Hostname != "" {
+		top = append(top, slog.String("hostname", h.opts.Hostname))
+	}
+	if len(tags) > 0 {
+		top = append(top, slog.String("ddtags", strings.Join(tags, ",")))
+	}
+	if h.opts.SpanContext != nil {
+		if traceID, spanID := h.opts.SpanContext(ctx); traceID != "" {
+			top = append(top, slog.String("dd.trace_id", datadogID(traceID)))
+			if spanID != "" {
+				top = append(top, slog.String("dd.span_id", datadogID(spanID)))
+			}
+		}
+	}
+	// the service attribute of the record is moved to the top
+	keep := service != ""
+	attrs := h.enc.Attrs(r, top...)
+	attrs = slices.DeleteFunc(attrs, func(a slog.Attr) bool {
+		if a.Key != "service" {
+			return false
+		}
+		if keep {
+			keep = false
+			return false
+		}
+		return true
+	})
+
+	buf := bufPool.Get().(*[]byte)
+	b := appendJSONAttrs((*buf)[:0], attrs)
+	if len(b) > datadogMaxEntryBytes {
+		h.w.dropped.Add(1)
+	} else {
+		h.w.add(time.Time{}, b)
+	}
+	if cap(b) <= 64<<10 {
+		*buf = b
+		bufPool.Put(buf)
+	}
+	return nil
+}
+
+// Flush sends the pending records.
+func (h *DatadogHandler) Flush() error {
+	return h.w.Flush()
+}
+
+// Close sends the pending records and stops the background goroutine.
+func (h *DatadogHandler) Close() error {
+	return h.w.Close()
+}
+
+// Stats returns the delivery counters of the handler.
+func (h *DatadogHandler) Stats() Stats {
+	return h.w.Stats()
+}
+
+func (h *DatadogHandler) sharedState() any {
+	return h.w
+}
+
+// datadogRenameBuiltin renames the built-in attributes to the Datadog reserved ones.
+func datadogRenameBuiltin(a slog.Attr) slog.Attr {
+	switch a.Key {
+	case slog.TimeKey:
+		a.Key = "timestamp"
+	case slog.LevelKey:
+		a.Key = "status"
+		if level, ok := a.Value.Any().(slog.Level); ok {
+			a.Value = slog.StringValue(strings.ToLower(level.String()))
+		}
+	case slog.MessageKey:
+		a.Key = "message"
+	case slog.SourceKey:
+		if src, ok := a.Value.Any().(*slog.Source); ok {
+			return slog.Group("logger",
+				slog.String("file", src.File),
+				slog.Int("line", src.Line),
+				slog.String("method_name", src.Function))
+		}
+	}
+	return a
+}
+
+// post sends the entries as a gzip-compressed JSON array.
+func (w *datadogWriter) post(ctx context.Context, entries []batchEntry) error {
+	body := new(bytes.Buffer)
+	zw := gzip.NewWriter(body)
+	_, _ = zw.Write([]byte("["))
+	for i, e := range entries {
+		if i > 0 {
+			_, _ = zw.Write([]byte(","))
+		}
+		_, _ = zw.Write(e.data)
+	}
+	_, _ = zw.Write([]byte("]"))
+	if err := zw.Close(); err != nil {
+		return permanentError{err}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, body)
+	if err != nil {
+		return permanentError{err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("DD-API-KEY", w.apiKey)
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	if resp.StatusCode < 300 {
+		return nil
+	}
+	err = fmt.Errorf("datadog: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusRequestTimeout {
+		return err
+	}
+	return permanentError{err}
+}
+
+// datadogID converts a 128-bit hex trace ID or a 64-bit hex span ID to the
+// decimal 64-bit form Datadog correlates on. Decimal IDs are returned
+// unchanged, so a 16-digit span ID without hex letters is taken as decimal.
+func datadogID(id string) string {
+	if len(id) != 32 && (len(id) != 16 || !strings.ContainsAny(id, "abcdefABCDEF")) {
+		return id
+	}
+	n, err := strconv.ParseUint(id[len(id)-16:], 16, 64)
+	if err != nil {
+		return id
+	}
+	return strconv.FormatUint(n, 10)
+}
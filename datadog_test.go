@@ -0,0 +1,110 @@
+package slogx
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDatadogID(t *testing.T) {
+	for _, tt := range []struct {
+		id, expected string
+	}{
+		{"", ""},
+		{"1234567890123456", "1234567890123456"},
+		{"12345", "12345"},
+		{"00000000000000ff", "255"},
+		{"00000000000000FF", "255"},
+		{"4bf92f3577b34da6a3ce929d0e0e4736", "11803532876627986230"},
+		{"0af7651916cd43dd8448eb211c80319c", "9532127138774266268"},
+		{"zzzzzzzzzzzzzzzz", "zzzzzzzzzzzzzzzz"},
+	} {
+		if got := datadogID(tt.id); got != tt.expected {
+			t.Errorf("datadogID(%q) = %q, expected %q", tt.id, got, tt.expected)
+		}
+	}
+}
+
+type datadogServer struct {
+	mu      sync.Mutex
+	apiKeys []string
+	batches [][]map[string]any
+}
+
+func (s *datadogServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	zr, err := gzip.NewReader(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var batch []map[string]any
+	if err := json.NewDecoder(zr).Decode(&batch); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	s.apiKeys = append(s.apiKeys, r.Header.Get("DD-API-KEY"))
+	s.batches = append(s.batches, batch)
+	s.mu.Unlock()
+}
+
+func TestDatadogHandler(t *testing.T) {
+	srv := new(datadogServer)
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	h := NewDatadogHandler(DatadogOptions{
+		APIKey:        "key",
+		URL:           ts.URL,
+		Service:       "api",
+		Tags:          []string{"team:core"},
+		TagKeys:       []string{"env"},
+		FlushInterval: time.Hour,
+		SpanContext: func(context.Context) (string, string) {
+			return "4bf92f3577b34da6a3ce929d0e0e4736", "00000000000000ff"
+		},
+	})
+	logger := slog.New(h)
+	logger.Info("first", "env", "prod")
+	logger.Warn("second", "service", "worker")
+	if err := h.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	logger.Error("third")
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(srv.batches) != 2 || len(srv.batches[0]) != 2 || len(srv.batches[1]) != 1 {
+		t.Fatalf("unexpected batches %v", srv.batches)
+	}
+	if srv.apiKeys[0] != "key" {
+		t.Fatalf("unexpected API key %q", srv.apiKeys[0])
+	}
+	for i, expected := range []map[string]any{
+		{"status": "info", "message": "first", "service": "api", "ddtags": "team:core,env:prod", "env": "prod"},
+		{"status": "warn", "message": "second", "service": "worker", "ddtags": "team:core"},
+	} {
+		e := srv.batches[0][i]
+		for k, v := range expected {
+			if e[k] != v {
+				t.Errorf("entry %d: %s = %v, expected %v", i, k, e[k], v)
+			}
+		}
+		if e["dd.trace_id"] != "11803532876627986230" || e["dd.span_id"] != "255" {
+			t.Errorf("entry %d: unexpected trace %v %v", i, e["dd.trace_id"], e["dd.span_id"])
+		}
+		if _, ok := e["timestamp"]; !ok {
+			t.Errorf("entry %d: no timestamp", i)
+		}
+	}
+	if s := h.Stats(); s.Handled != 3 || s.Dropped != 0 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+}
@@ -0,0 +1,185 @@
+package slogx
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// RecordEncoder converts records, with the attributes and groups of the
+// WithAttrs/WithGroup calls of a handler, into JSON objects or maps. It is the
+// common encoding of the sink handlers of this package, and is meant for
+// custom sinks too: a handler keeps a RecordEncoder, derives new ones in its
+// own WithAttrs and WithGroup, and encodes in Handle:
+//
+//	func (h *MySink) Handle(_ context.Context, r slog.Record) error {
+//		return h.send(h.enc.AppendJSON(nil, r))
+//	}
+//
+// Groups follow the slog.Handler rules: record attributes are nested in all
+// the groups, attributes of WithAttrs in the groups opened before them, and
+// empty groups are omitted.
+type RecordEncoder struct {
+	opts  slog.HandlerOptions
+	state attrState
+
+	// Rename is applied to the built-in time, level, source and message
+	// attributes after ReplaceAttr, e.g. to map them to the reserved fields
+	// of a log service.
+	Rename func(slog.Attr) slog.Attr
+}
+
+// NewRecordEncoder creates RecordEncoder with the ReplaceAttr and AddSource options
+func NewRecordEncoder(opts *slog.HandlerOptions) RecordEncoder {
+	var e RecordEncoder
+	if opts != nil {
+		e.opts = *opts
+	}
+	return e
+}
+
+func (e RecordEncoder) WithAttrs(attrs []slog.Attr) RecordEncoder {
+	e.state = e.state.withAttrs(attrs)
+	return e
+}
+
+func (e RecordEncoder) WithGroup(name string) RecordEncoder {
+	e.state = e.state.withGroup(name)
+	return e
+}
+
+// Attrs returns the built-in attributes, then top, then the handler and record
+// attributes nested in their groups.
+func (e RecordEncoder) Attrs(r slog.Record, top ...slog.Attr) []slog.Attr {
+	xs := builtinAttrs(r, &e.opts, e.Rename)
+	xs = append(xs, top...)
+	return append(xs, e.state.attrs(r, e.opts.ReplaceAttr)...)
+}
+
+// AppendJSON appends the JSON object of the record to b, with the attributes
+// ordered as Attrs returns them. No newline is appended.
+func (e RecordEncoder) AppendJSON(b []byte, r slog.Record, top ...slog.Attr) []byte {
+	return appendJSONAttrs(b, e.Attrs(r, top...))
+}
+
+// Map returns the record as a map, groups as nested maps.
+func (e RecordEncoder) Map(r slog.Record, top ...slog.Attr) map[string]any {
+	return attrsMap(e.Attrs(r, top...))
+}
+
+func attrsMap(attrs []slog.Attr) map[string]any {
+	m := make(map[string]any, len(attrs))
+	for _, a := range attrs {
+		if a.Value.Kind() == slog.KindGroup {
+			m[a.Key] = attrsMap(a.Value.Group())
+		} else {
+			m[a.Key] = a.Value.Any()
+		}
+	}
+	return m
+}
+
+func appendJSONAttrs(b []byte, attrs []slog.Attr) []byte {
+	b = append(b, '{')
+	for i, a := range attrs {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = appendJSONString(b, a.Key)
+		b = append(b, ':')
+		b = appendJSONValue(b, a.Value)
+	}
+	return append(b, '}')
+}
+
+func appendJSONValue(b []byte, v slog.Value) []byte {
+	switch v.Kind() {
+	case slog.KindString:
+		return appendJSONString(b, v.String())
+	case slog.KindInt64:
+		return strconv.AppendInt(b, v.Int64(), 10)
+	case slog.KindUint64:
+		return strconv.AppendUint(b, v.Uint64(), 10)
+	case slog.KindFloat64:
+		f := v.Float64()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return appendJSONString(b, strconv.FormatFloat(f, 'g', -1, 64))
+		}
+		return strconv.AppendFloat(b, f, 'g', -1, 64)
+	case slog.KindBool:
+		return strconv.AppendBool(b, v.Bool())
+	case slog.KindDuration:
+		return strconv.AppendInt(b, int64(v.Duration()), 10)
+	case slog.KindTime:
+		b = append(b, '"')
+		b = v.Time().AppendFormat(b, time.RFC3339Nano)
+		return append(b, '"')
+	case slog.KindGroup:
+		return appendJSONAttrs(b, v.Group())
+	case slog.KindLogValuer:
+		return appendJSONValue(b, v.Resolve())
+	}
+	switch x := v.Any().(type) {
+	case nil:
+		return append(b, "null"...)
+	case json.Marshaler:
+	case error:
+		return appendJSONString(b, x.Error())
+	case slog.Level:
+		return appendJSONString(b, x.String())
+	}
+	j, err := json.Marshal(v.Any())
+	if err != nil {
+		return appendJSONString(b, fmt.Sprintf("!ERROR:%v", err))
+	}
+	return append(b, j...)
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString appends s quoted and escaped as a JSON string, with
+// invalid UTF-8 replaced by U+FFFD.
+func appendJSONString(b []byte, s string) []byte {
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				b = append(b, '\\', c)
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			default:
+				b = append(b, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, s[start:i]...)
+			b = append(b, `�`...)
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"')
+}
@@ -0,0 +1,71 @@
+package slogx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+	"testing"
+	"testing/slogtest"
+	"time"
+)
+
+// encoderHandler is the minimal sink built on RecordEncoder.
+type encoderHandler struct {
+	enc   RecordEncoder
+	lines *[][]byte
+}
+
+func (h encoderHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h encoderHandler) Handle(_ context.Context, r slog.Record) error {
+	*h.lines = append(*h.lines, h.enc.AppendJSON(nil, r))
+	return nil
+}
+
+func (h encoderHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h.enc = h.enc.WithAttrs(attrs)
+	return h
+}
+
+func (h encoderHandler) WithGroup(name string) slog.Handler {
+	h.enc = h.enc.WithGroup(name)
+	return h
+}
+
+func TestRecordEncoderSlogtest(t *testing.T) {
+	var lines [][]byte
+	h := encoderHandler{enc: NewRecordEncoder(nil), lines: &lines}
+	err := slogtest.TestHandler(h, func() []map[string]any {
+		var ms []map[string]any
+		for _, line := range lines {
+			var m map[string]any
+			if err := json.Unmarshal(line, &m); err != nil {
+				t.Fatalf("%s: %v", line, err)
+			}
+			ms = append(ms, m)
+		}
+		return ms
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRecordEncoderValues(t *testing.T) {
+	enc := NewRecordEncoder(nil)
+	r := slog.NewRecord(time.Time{}, slog.LevelWarn, "a \"b\"\n\x01", 0)
+	r.AddAttrs(
+		slog.Float64("nan", math.NaN()),
+		slog.Duration("d", time.Second),
+		slog.Any("err", errors.New("failed")),
+		slog.Any("xs", []int{1, 2}),
+		slog.String("bad", "\xff"),
+	)
+	got := string(enc.AppendJSON(nil, r))
+	expected := `{"level":"WARN","msg":"a \"b\"\n\u0001","nan":"NaN","d":1000000000,"err":"failed","xs":[1,2],"bad":"�"}`
+	if got != expected {
+		t.Fatalf("\n%s\nexpected\n%s", got, expected)
+	}
+}
@@ -0,0 +1,72 @@
+package slogx
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Flusher is implemented by handlers and writers buffering records.
+type Flusher interface {
+	Flush() error
+}
+
+var flushers = struct {
+	sync.Mutex
+	m    map[int]Flusher
+	next int
+}{m: make(map[int]Flusher)}
+
+// RegisterFlusher adds f to the process-wide set flushed by FlushAll.
+// The returned function removes it from the set. The handlers of this package
+// sending records in the background, like DatadogHandler, CloudWatchHandler
+// and NotifyHandler, register themselves until closed.
+//
+//	w := slogx.NewCompressedFileWriter("batch.log", 64<<20, slogx.Gzip)
+//	defer slogx.RegisterFlusher(w)()
+func RegisterFlusher(f Flusher) (unregister func()) {
+	flushers.Lock()
+	defer flushers.Unlock()
+
+	id := flushers.next
+	flushers.next++
+	flushers.m[id] = f
+	return func() {
+		flushers.Lock()
+		delete(flushers.m, id)
+		flushers.Unlock()
+	}
+}
+
+// FlushAll flushes all the registered flushers concurrently and waits for them
+// until ctx is done. It is meant to be called before the process exits,
+// including from panic handlers:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+//	defer cancel()
+//	_ = slogx.FlushAll(ctx)
+func FlushAll(ctx context.Context) error {
+	flushers.Lock()
+	xs := make([]Flusher, 0, len(flushers.m))
+	for _, f := range flushers.m {
+		xs = append(xs, f)
+	}
+	flushers.Unlock()
+
+	errs := make(chan error, len(xs))
+	for _, f := range xs {
+		go func(f Flusher) {
+			errs <- f.Flush()
+		}(f)
+	}
+	var result []error
+	for range xs {
+		select {
+		case err := <-errs:
+			result = append(result, err)
+		case <-ctx.Done():
+			return errors.Join(append(result, ctx.Err())...)
+		}
+	}
+	return errors.Join(result...)
+}
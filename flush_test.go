@@ -0,0 +1,81 @@
+package slogx
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type flushCounter struct {
+	n atomic.Int32
+}
+
+func (f *flushCounter) Flush() error {
+	f.n.Add(1)
+	return nil
+}
+
+type notifyFunc func(ctx context.Context, text string) error
+
+func (f notifyFunc) Notify(ctx context.Context, text string) error {
+	return f(ctx, text)
+}
+
+func registeredFlushers() int {
+	flushers.Lock()
+	defer flushers.Unlock()
+	return len(flushers.m)
+}
+
+func TestRegisterFlusher(t *testing.T) {
+	f := new(flushCounter)
+	unregister := RegisterFlusher(f)
+	if err := FlushAll(context.Background()); err != nil || f.n.Load() != 1 {
+		t.Fatalf("got %v, %d flushes", err, f.n.Load())
+	}
+	unregister()
+	if err := FlushAll(context.Background()); err != nil || f.n.Load() != 1 {
+		t.Fatalf("got %v, %d flushes after unregister", err, f.n.Load())
+	}
+}
+
+func TestFlushAllHandlers(t *testing.T) {
+	n := registeredFlushers()
+	client := new(fakeCloudWatch)
+	cw := NewCloudWatchHandler(client, CloudWatchOptions{
+		Group:         "group",
+		Stream:        "stream",
+		FlushInterval: time.Hour,
+	})
+	var notified atomic.Int32
+	notify := NewNotifyHandler(notifyFunc(func(context.Context, string) error {
+		notified.Add(1)
+		return nil
+	}), nil)
+	if registeredFlushers() != n+2 {
+		t.Fatalf("handlers not registered: %d flushers", registeredFlushers()-n)
+	}
+
+	slog.New(cw).Info("first")
+	slog.New(notify).Error("failed")
+	if err := FlushAll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.puts) != 1 || notified.Load() != 1 {
+		t.Fatalf("unexpected puts %v, %d notifications", client.puts, notified.Load())
+	}
+
+	for range 2 {
+		if err := cw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := notify.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if registeredFlushers() != n {
+		t.Fatalf("handlers not unregistered: %d flushers", registeredFlushers()-n)
+	}
+}
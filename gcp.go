@@ -0,0 +1,175 @@
+package slogx
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+)
+
+// Cloud Logging special fields of structured payloads.
+const (
+	gcpSeverityKey       = "severity"
+	gcpMessageKey        = "message"
+	gcpSourceLocationKey = "logging.googleapis.com/sourceLocation"
+	gcpTraceKey          = "logging.googleapis.com/trace"
+	gcpSpanIDKey         = "logging.googleapis.com/spanId"
+	gcpTraceSampledKey   = "logging.googleapis.com/trace_sampled"
+)
+
+// GCPOptions configures GCPHandler.
+type GCPOptions struct {
+	slog.HandlerOptions
+
+	// ProjectID makes the trace ID a full resource name, projects/<ProjectID>/traces/<trace ID>,
+	// which is required for Cloud Logging to link the entry to Cloud Trace
+	ProjectID string
+
+	// TraceKey and SpanKey are the record attributes holding the trace and
+	// span IDs, e.g. stored in the context with slogctx.WithValues;
+	// "trace_id" and "span_id" by default
+	TraceKey string
+	SpanKey  string
+
+	// SpanContext returns the trace of the context, e.g. of the active
+	// OpenTelemetry span. It takes precedence over the record attributes.
+	SpanContext func(ctx context.Context) (traceID, spanID string, sampled bool)
+}
+
+// GCPHandler writes records as JSON lines with the Google Cloud Logging
+// special fields: severity, message, sourceLocation and trace, so the
+// stdout of GKE and Cloud Run workloads is parsed into structured entries.
+type GCPHandler struct {
+	opts GCPOptions
+	enc  RecordEncoder
+	mu   *sync.Mutex
+	w    io.Writer
+}
+
+var _ slog.Handler = (*GCPHandler)(nil)
+
+// NewGCPHandler creates GCPHandler writing to w
+func NewGCPHandler(w io.Writer, opts *GCPOptions) *GCPHandler {
+	h := &GCPHandler{
+		mu: new(sync.Mutex),
+		w:  w,
+	}
+	if opts != nil {
+		h.opts = *opts
+	}
+	if h.opts.TraceKey == "" {
+		h.opts.TraceKey = "trace_id"
+	}
+	if h.opts.SpanKey == "" {
+		h.opts.SpanKey = "span_id"
+	}
+	h.enc = NewRecordEncoder(&h.opts.HandlerOptions)
+	h.enc.Rename = gcpRenameBuiltin
+	return h
+}
+
+func (h *GCPHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return levelEnabled(&h.opts.HandlerOptions, level)
+}
+
+func (h *GCPHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.enc = h.enc.WithAttrs(attrs)
+	return &h2
+}
+
+func (h *GCPHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.enc = h.enc.WithGroup(name)
+	return &h2
+}
+
+// Handle moves the trace attributes of the record to the top level and
+// writes it with the handler attributes nested in their groups.
+func (h *GCPHandler) Handle(ctx context.Context, r slog.Record) error {
+	var traceID, spanID string
+	sampled := false
+	rest := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		switch a.Key {
+		case h.opts.TraceKey:
+			traceID = a.Value.Resolve().String()
+		case h.opts.SpanKey:
+			spanID = a.Value.Resolve().String()
+		default:
+			rest.AddAttrs(a)
+		}
+		return true
+	})
+	if h.opts.SpanContext != nil {
+		if t, s, ok := h.opts.SpanContext(ctx); t != "" {
+			traceID, spanID, sampled = t, s, ok
+		}
+	}
+
+	var top []slog.Attr
+	if traceID != "" {
+		if h.opts.ProjectID != "" {
+			traceID = "projects/" + h.opts.ProjectID + "/traces/" + traceID
+		}
+		top = append(top, slog.String(gcpTraceKey, traceID))
+		if spanID != "" {
+			top = append(top, slog.String(gcpSpanIDKey, spanID))
+		}
+		top = append(top, slog.Bool(gcpTraceSampledKey, sampled))
+	}
+
+	buf := bufPool.Get().(*[]byte)
+	b := h.enc.AppendJSON((*buf)[:0], rest, top...)
+	b = append(b, '\n')
+
+	h.mu.Lock()
+	_, err := h.w.Write(b)
+	h.mu.Unlock()
+
+	if cap(b) <= 64<<10 {
+		*buf = b
+		bufPool.Put(buf)
+	}
+	return err
+}
+
+// gcpRenameBuiltin maps the built-in attributes to the Cloud Logging special fields.
+func gcpRenameBuiltin(a slog.Attr) slog.Attr {
+	switch a.Key {
+	case slog.LevelKey:
+		if level, ok := a.Value.Any().(slog.Level); ok {
+			return slog.String(gcpSeverityKey, gcpSeverity(level))
+		}
+	case slog.MessageKey:
+		a.Key = gcpMessageKey
+	case slog.SourceKey:
+		if src, ok := a.Value.Any().(*slog.Source); ok {
+			return slog.Group(gcpSourceLocationKey,
+				slog.String("file", src.File),
+				slog.Int("line", src.Line),
+				slog.String("function", src.Function))
+		}
+	}
+	return a
+}
+
+func gcpSeverity(level slog.Level) string {
+	switch {
+	case level < slog.LevelInfo:
+		return "DEBUG"
+	case level < slog.LevelInfo+2:
+		return "INFO"
+	case level < slog.LevelWarn:
+		return "NOTICE"
+	case level < slog.LevelError:
+		return "WARNING"
+	case level < slog.LevelError+4:
+		return "ERROR"
+	case level < slog.LevelError+8:
+		return "CRITICAL"
+	case level < slog.LevelError+12:
+		return "ALERT"
+	}
+	return "EMERGENCY"
+}
@@ -0,0 +1,72 @@
+package slogx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestGCPSeverity(t *testing.T) {
+	for level, expected := range map[slog.Level]string{
+		slog.LevelDebug:      "DEBUG",
+		slog.LevelInfo:       "INFO",
+		slog.LevelInfo + 2:   "NOTICE",
+		slog.LevelWarn:       "WARNING",
+		slog.LevelError:      "ERROR",
+		slog.LevelError + 4:  "CRITICAL",
+		slog.LevelError + 8:  "ALERT",
+		slog.LevelError + 12: "EMERGENCY",
+	} {
+		if s := gcpSeverity(level); s != expected {
+			t.Errorf("%v: got %s, expected %s", level, s, expected)
+		}
+	}
+}
+
+func TestGCPHandler(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := slog.New(NewGCPHandler(buf, &GCPOptions{
+		HandlerOptions: slog.HandlerOptions{
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if a.Key == slog.TimeKey && len(groups) == 0 {
+					return slog.Attr{}
+				}
+				return a
+			},
+		},
+		ProjectID: "p",
+	}))
+	logger.WithGroup("req").Warn("slow", "trace_id", "t1", "span_id", "s1", "ms", 120)
+
+	expected := `{"severity":"WARNING","message":"slow","logging.googleapis.com/trace":"projects/p/traces/t1","logging.googleapis.com/spanId":"s1","logging.googleapis.com/trace_sampled":false,"req":{"ms":120}}` + "\n"
+	if buf.String() != expected {
+		t.Fatalf("\n%s\nexpected\n%s", buf, expected)
+	}
+}
+
+func TestGCPHandlerSpanContext(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := slog.New(NewGCPHandler(buf, &GCPOptions{
+		HandlerOptions: slog.HandlerOptions{AddSource: true},
+		SpanContext: func(context.Context) (string, string, bool) {
+			return "t2", "s2", true
+		},
+	}))
+	logger.ErrorContext(context.Background(), "failed", "trace_id", "t1")
+
+	var m map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m[gcpTraceKey] != "t2" || m[gcpSpanIDKey] != "s2" || m[gcpTraceSampledKey] != true || m[gcpSeverityKey] != "ERROR" {
+		t.Fatalf("unexpected entry %v", m)
+	}
+	loc, _ := m[gcpSourceLocationKey].(map[string]any)
+	if file, _ := loc["file"].(string); !strings.HasSuffix(file, "gcp_test.go") || loc["line"] == nil ||
+		!strings.HasSuffix(loc["function"].(string), "TestGCPHandlerSpanContext") {
+		t.Fatalf("unexpected source location %v", loc)
+	}
+}
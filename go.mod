@@ -2,10 +2,8 @@ module github.com/fpawel/slogx
 
 go 1.22
 
-require github.com/fatih/color v1.16.0
-
 require (
-	github.com/mattn/go-colorable v0.1.13 // indirect
-	github.com/mattn/go-isatty v0.0.20 // indirect
-	golang.org/x/sys v0.14.0 // indirect
+	github.com/mattn/go-colorable v0.1.13
+	github.com/mattn/go-isatty v0.0.20
+	golang.org/x/sys v0.14.0
 )
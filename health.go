@@ -0,0 +1,100 @@
+package slogx
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+type (
+	// SinkHealth is the status of a handler implementing StatsProvider,
+	// reported by HealthHTTPHandler.
+	SinkHealth struct {
+		Name          string     `json:"name"`
+		Healthy       bool       `json:"healthy"`
+		Connected     *bool      `json:"connected,omitempty"` // of the handlers with a Connected() bool method
+		Queued        int        `json:"queued"`
+		Handled       uint64     `json:"handled"`
+		Dropped       uint64     `json:"dropped"`
+		Retried       uint64     `json:"retried"`
+		LastFlushTime *time.Time `json:"last_flush_time,omitempty"`
+		LastError     string     `json:"last_error,omitempty"`
+		LastErrorTime *time.Time `json:"last_error_time,omitempty"`
+	}
+
+	// HealthReport is the response of HealthHTTPHandler.
+	HealthReport struct {
+		Healthy bool         `json:"healthy"`
+		Sinks   []SinkHealth `json:"sinks"`
+	}
+)
+
+// HealthHTTPHandler returns a handler of readiness probes and dashboards
+// reporting the status of the sinks among the handlers and the handlers they
+// wrap, as found by Close. It responds with HealthReport JSON and status
+// 503 Service Unavailable when any sink is unhealthy, i.e. its last delivery
+// attempt failed.
+//
+//	http.Handle("/debug/logging", slogx.HealthHTTPHandler(datadogHandler, socketHandler))
+func HealthHTTPHandler(handlers ...slog.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		report := Health(handlers...)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if !report.Healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
+
+// Health returns the status of the sinks among the handlers and the handlers they wrap.
+// A sink reached several times, e.g. through the copies made by WithAttrs, is reported once.
+func Health(handlers ...slog.Handler) HealthReport {
+	report := HealthReport{Healthy: true, Sinks: []SinkHealth{}}
+	seen := make(map[any]bool) // sinks reached several times
+	for _, h := range handlers {
+		walkHandlers(h, func(h slog.Handler) {
+			p, ok := h.(StatsProvider)
+			if !ok {
+				return
+			}
+			if key, ok := handlerKey(h); ok {
+				if seen[key] {
+					return
+				}
+				seen[key] = true
+			}
+			s := sinkHealth(fmt.Sprintf("%T", h), p)
+			report.Healthy = report.Healthy && s.Healthy
+			report.Sinks = append(report.Sinks, s)
+		})
+	}
+	return report
+}
+
+func sinkHealth(name string, p StatsProvider) SinkHealth {
+	s := p.Stats()
+	x := SinkHealth{
+		Name:    name,
+		Healthy: s.LastError == nil || s.LastFlushTime.After(s.LastErrorTime),
+		Queued:  s.Queued,
+		Handled: s.Handled,
+		Dropped: s.Dropped,
+		Retried: s.Retried,
+	}
+	if c, ok := p.(interface{ Connected() bool }); ok {
+		connected := c.Connected()
+		x.Connected = &connected
+	}
+	if !s.LastFlushTime.IsZero() {
+		x.LastFlushTime = &s.LastFlushTime
+	}
+	if s.LastError != nil {
+		x.LastError = s.LastError.Error()
+		x.LastErrorTime = &s.LastErrorTime
+	}
+	return x
+}
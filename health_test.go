@@ -0,0 +1,81 @@
+package slogx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type healthSink struct {
+	slog.Handler
+	stats Stats
+}
+
+func (h *healthSink) Stats() Stats {
+	return h.stats
+}
+
+// healthValueSink is a sink of a not comparable value type.
+type healthValueSink struct {
+	slog.Handler
+	tags []string
+}
+
+func (h healthValueSink) Stats() Stats {
+	return Stats{Handled: uint64(len(h.tags))}
+}
+
+func TestHealth(t *testing.T) {
+	discard := slog.NewJSONHandler(io.Discard, nil)
+	tm := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	ok := &healthSink{discard, Stats{Handled: 3, LastFlushTime: tm}}
+	recovered := &healthSink{discard, Stats{Handled: 1, LastError: errors.New("timeout"), LastErrorTime: tm, LastFlushTime: tm.Add(time.Second)}}
+
+	report := Health(ok, closeFanout{discard, []slog.Handler{ok, recovered, healthValueSink{discard, []string{"a", "b"}}}})
+	if !report.Healthy || len(report.Sinks) != 3 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if s := report.Sinks[1]; !s.Healthy || s.LastError != "timeout" || s.Handled != 1 {
+		t.Fatalf("unexpected sink %+v", s)
+	}
+	if s := report.Sinks[2]; s.Name != "slogx.healthValueSink" || s.Handled != 2 {
+		t.Fatalf("unexpected sink %+v", s)
+	}
+}
+
+func TestHealthHTTPHandler(t *testing.T) {
+	tm := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	failing := &healthSink{slog.NewJSONHandler(io.Discard, nil), Stats{Dropped: 2, LastError: errors.New("refused"), LastErrorTime: tm}}
+
+	w := httptest.NewRecorder()
+	HealthHTTPHandler(failing).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("unexpected response %d %v", w.Code, w.Header())
+	}
+	var report HealthReport
+	if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Healthy || len(report.Sinks) != 1 || report.Sinks[0].Name != "*slogx.healthSink" ||
+		report.Sinks[0].Dropped != 2 || report.Sinks[0].LastError != "refused" || !report.Sinks[0].LastErrorTime.Equal(tm) {
+		t.Fatalf("unexpected report %+v", report)
+	}
+}
+
+func TestHealthDerivedHandlers(t *testing.T) {
+	n := NewNotifyHandler(notifyFunc(func(context.Context, string) error { return nil }), nil)
+	defer n.Close()
+	discard := slog.NewJSONHandler(io.Discard, nil)
+	derived := n.WithAttrs([]slog.Attr{slog.String("app", "api")})
+
+	report := Health(n, derived, closeFanout{discard, []slog.Handler{derived.WithGroup("req"), n.WithGroup("req")}})
+	if len(report.Sinks) != 1 || report.Sinks[0].Name != "*slogx.NotifyHandler" {
+		t.Fatalf("unexpected report %+v", report)
+	}
+}
@@ -0,0 +1,125 @@
+package slogx
+
+import (
+	"bufio"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// K8sMetadata is the identity of the Kubernetes workload the process runs in.
+type K8sMetadata struct {
+	Pod       string
+	Namespace string
+	Node      string
+	PodIP     string
+	Labels    map[string]string
+}
+
+// DefaultK8sPodInfoDir is the conventional mount path of the downward API volume.
+const DefaultK8sPodInfoDir = "/etc/podinfo"
+
+const k8sNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+var loadK8sMetadata = sync.OnceValue(func() K8sMetadata {
+	return LoadK8sMetadata(DefaultK8sPodInfoDir)
+})
+
+// WithK8sMetadata returns h with the "k8s" group of the workload metadata
+// added to every record. The metadata is read once per process.
+func WithK8sMetadata(h slog.Handler) slog.Handler {
+	m := loadK8sMetadata()
+	if m.Pod == "" && m.Namespace == "" {
+		return h
+	}
+	return h.WithAttrs([]slog.Attr{m.Attr()})
+}
+
+// LoadK8sMetadata reads the workload metadata exposed with the downward API:
+// the POD_NAME, POD_NAMESPACE, NODE_NAME and POD_IP environment variables,
+// and the labels file of the volume mounted at podInfoDir.
+// The pod name falls back to HOSTNAME and the namespace to the one of the
+// service account.
+//
+//	env:
+//	- name: POD_NAME
+//	  valueFrom: {fieldRef: {fieldPath: metadata.name}}
+//	volumes:
+//	- name: podinfo
+//	  downwardAPI:
+//	    items:
+//	    - path: labels
+//	      fieldRef: {fieldPath: metadata.labels}
+func LoadK8sMetadata(podInfoDir string) K8sMetadata {
+	m := K8sMetadata{
+		Pod:       os.Getenv("POD_NAME"),
+		Namespace: os.Getenv("POD_NAMESPACE"),
+		Node:      os.Getenv("NODE_NAME"),
+		PodIP:     os.Getenv("POD_IP"),
+		Labels:    readK8sLabels(filepath.Join(podInfoDir, "labels")),
+	}
+	if m.Namespace == "" {
+		if b, err := os.ReadFile(k8sNamespaceFile); err == nil {
+			m.Namespace = strings.TrimSpace(string(b))
+		}
+	}
+	if m.Pod == "" && m.Namespace != "" {
+		m.Pod = os.Getenv("HOSTNAME")
+	}
+	return m
+}
+
+// Attr returns the "k8s" group of the non-empty metadata fields.
+func (m K8sMetadata) Attr() slog.Attr {
+	var attrs []any
+	for _, x := range []struct{ key, value string }{
+		{"pod", m.Pod},
+		{"namespace", m.Namespace},
+		{"node", m.Node},
+		{"pod_ip", m.PodIP},
+	} {
+		if x.value != "" {
+			attrs = append(attrs, slog.String(x.key, x.value))
+		}
+	}
+	if len(m.Labels) > 0 {
+		keys := make([]string, 0, len(m.Labels))
+		for k := range m.Labels {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		labels := make([]any, len(keys))
+		for i, k := range keys {
+			labels[i] = slog.String(k, m.Labels[k])
+		}
+		attrs = append(attrs, slog.Group("labels", labels...))
+	}
+	return slog.Group("k8s", attrs...)
+}
+
+// readK8sLabels parses the key="value" lines of a downward API labels file.
+func readK8sLabels(path string) map[string]string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	labels := make(map[string]string)
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		k, v, ok := strings.Cut(sc.Text(), "=")
+		if !ok {
+			continue
+		}
+		if s, err := strconv.Unquote(v); err == nil {
+			v = s
+		}
+		labels[k] = v
+	}
+	return labels
+}
@@ -0,0 +1,49 @@
+package slogx
+
+import (
+	"bytes"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadK8sMetadata(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "labels"), []byte("app=\"api\"\ntier=\"backend\"\ninvalid\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("POD_NAME", "")
+	t.Setenv("POD_NAMESPACE", "prod")
+	t.Setenv("NODE_NAME", "node-1")
+	t.Setenv("POD_IP", "")
+	t.Setenv("HOSTNAME", "api-7d9f")
+
+	m := LoadK8sMetadata(dir)
+	if m.Pod != "api-7d9f" || m.Namespace != "prod" || m.Node != "node-1" || m.PodIP != "" ||
+		!maps.Equal(m.Labels, map[string]string{"app": "api", "tier": "backend"}) {
+		t.Fatalf("unexpected metadata %+v", m)
+	}
+
+	buf := new(bytes.Buffer)
+	slog.New(routeTextHandler(buf).WithAttrs([]slog.Attr{m.Attr()})).Info("x")
+	expected := "msg=x k8s.pod=api-7d9f k8s.namespace=prod k8s.node=node-1 k8s.labels.app=api k8s.labels.tier=backend\n"
+	if buf.String() != expected {
+		t.Fatalf("\n%s\nexpected\n%s", buf, expected)
+	}
+}
+
+func TestLoadK8sMetadataOutside(t *testing.T) {
+	if _, err := os.Stat(k8sNamespaceFile); err == nil {
+		t.Skip("running in Kubernetes")
+	}
+	for _, k := range []string{"POD_NAME", "POD_NAMESPACE", "NODE_NAME", "POD_IP"} {
+		t.Setenv(k, "")
+	}
+	t.Setenv("HOSTNAME", "laptop")
+
+	if m := LoadK8sMetadata(t.TempDir()); m.Pod != "" || m.Namespace != "" || m.Labels != nil {
+		t.Fatalf("unexpected metadata %+v", m)
+	}
+}
@@ -0,0 +1,53 @@
+package slogx
+
+import (
+	"context"
+	"log/slog"
+)
+
+// LevelHandler passes to the inner handler only the records at or above its
+// level, whatever the level of the inner handler, so sinks of one logger can
+// have their own levels, e.g. the console at Debug, a file sink wrapped at
+// Info and an alerting sink wrapped at Error:
+//
+//	file = slogx.NewLevelHandler(slog.LevelInfo, file)
+//	alerts = slogx.NewLevelHandler(slog.LevelError, alerts)
+//
+// The level can be changed at runtime with a *slog.LevelVar.
+type LevelHandler struct {
+	level slog.Leveler
+	inner slog.Handler
+}
+
+var _ slog.Handler = (*LevelHandler)(nil)
+
+// NewLevelHandler creates LevelHandler wrapping inner
+func NewLevelHandler(level slog.Leveler, inner slog.Handler) *LevelHandler {
+	return &LevelHandler{level: level, inner: inner}
+}
+
+// Unwrap returns the inner handler.
+func (h *LevelHandler) Unwrap() slog.Handler {
+	return h.inner
+}
+
+// Enabled reports whether the level is at or above the handler level and the
+// inner handler is enabled for it.
+func (h *LevelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level() && h.inner.Enabled(ctx, level)
+}
+
+func (h *LevelHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level < h.level.Level() {
+		return nil
+	}
+	return h.inner.Handle(ctx, r)
+}
+
+func (h *LevelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &LevelHandler{level: h.level, inner: h.inner.WithAttrs(attrs)}
+}
+
+func (h *LevelHandler) WithGroup(name string) slog.Handler {
+	return &LevelHandler{level: h.level, inner: h.inner.WithGroup(name)}
+}
@@ -0,0 +1,39 @@
+package slogx
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestLevelHandler(t *testing.T) {
+	buf := new(bytes.Buffer)
+	level := new(slog.LevelVar)
+	level.Set(slog.LevelWarn)
+	h := NewLevelHandler(level, routeTextHandler(buf))
+	logger := slog.New(h).With("app", "api")
+
+	logger.Info("hidden")
+	logger.Warn("shown")
+	level.Set(slog.LevelInfo)
+	logger.Info("changed")
+	// the records passed to Handle directly are filtered too
+	_ = h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelDebug, "direct", 0))
+
+	if buf.String() != "msg=shown app=api\nmsg=changed app=api\n" {
+		t.Fatalf("got\n%s", buf)
+	}
+}
+
+func TestLevelHandlerInnerLevel(t *testing.T) {
+	inner := slog.NewTextHandler(new(bytes.Buffer), &slog.HandlerOptions{Level: slog.LevelError})
+	h := NewLevelHandler(slog.LevelDebug, inner)
+	if h.Enabled(context.Background(), slog.LevelWarn) || !h.Enabled(context.Background(), slog.LevelError) {
+		t.Fatal("inner level not respected")
+	}
+	if h.Unwrap() != inner {
+		t.Fatal("unexpected Unwrap")
+	}
+}
@@ -0,0 +1,217 @@
+package slogx
+
+import (
+	"context"
+	"encoding"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// LogfmtHandler writes records as logfmt lines for Heroku-style drains and
+// Loki pipelines:
+//
+//	time=2024-03-01T12:30:00.123Z level=INFO msg="request done" http.status=200 http.path=/api
+//
+// Group names are joined to the keys with dots. Strings are quoted when they
+// are empty or contain spaces, quotes, '=' or non-printable characters.
+// Attributes of WithAttrs are formatted once, when the handler is created.
+type LogfmtHandler struct {
+	opts         slog.HandlerOptions
+	preformatted []byte
+	prefix       string // group names of WithGroup calls, each followed by a dot
+	groups       []string
+	mu           *sync.Mutex
+	w            io.Writer
+}
+
+var _ slog.Handler = (*LogfmtHandler)(nil)
+
+// NewLogfmtHandler creates LogfmtHandler writing to w
+func NewLogfmtHandler(w io.Writer, opts *slog.HandlerOptions) *LogfmtHandler {
+	h := &LogfmtHandler{
+		mu: new(sync.Mutex),
+		w:  w,
+	}
+	if opts != nil {
+		h.opts = *opts
+	}
+	return h
+}
+
+func (h *LogfmtHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return levelEnabled(&h.opts, level)
+}
+
+func (h *LogfmtHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.preformatted = slices.Clip(h.preformatted)
+	for _, a := range attrs {
+		h2.preformatted = h.appendAttr(h2.preformatted, h.prefix, h.groups, a)
+	}
+	return &h2
+}
+
+func (h *LogfmtHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix = h.prefix + name + "."
+	h2.groups = append(slices.Clip(h.groups), name)
+	return &h2
+}
+
+func (h *LogfmtHandler) Handle(_ context.Context, r slog.Record) error {
+	buf := bufPool.Get().(*[]byte)
+	b := (*buf)[:0]
+
+	if !r.Time.IsZero() {
+		b = h.appendBuiltin(b, slog.Time(slog.TimeKey, r.Time))
+	}
+	b = h.appendBuiltin(b, slog.Any(slog.LevelKey, r.Level))
+	if h.opts.AddSource && r.PC != 0 {
+		src := recordSource(r)
+		b = h.appendBuiltin(b, slog.Any(slog.SourceKey, &src))
+	}
+	b = h.appendBuiltin(b, slog.String(slog.MessageKey, r.Message))
+	b = append(b, h.preformatted...)
+	r.Attrs(func(a slog.Attr) bool {
+		b = h.appendAttr(b, h.prefix, h.groups, a)
+		return true
+	})
+	start := 0
+	if len(b) > 0 && b[0] == ' ' {
+		start = 1
+	}
+	b = append(b, '\n')
+
+	h.mu.Lock()
+	_, err := h.w.Write(b[start:])
+	h.mu.Unlock()
+
+	if cap(b) <= 64<<10 {
+		*buf = b[:0]
+		bufPool.Put(buf)
+	}
+	return err
+}
+
+func (h *LogfmtHandler) appendBuiltin(b []byte, a slog.Attr) []byte {
+	if h.opts.ReplaceAttr != nil {
+		a = h.opts.ReplaceAttr(nil, a)
+		if a.Equal(slog.Attr{}) {
+			return b
+		}
+	}
+	if src, ok := a.Value.Any().(*slog.Source); ok {
+		a.Value = slog.StringValue(src.File + ":" + strconv.Itoa(src.Line))
+	}
+	return h.appendPair(b, a.Key, a.Value)
+}
+
+// appendAttr appends a space and the attribute, with nested groups flattened.
+func (h *LogfmtHandler) appendAttr(b []byte, prefix string, groups []string, a slog.Attr) []byte {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() == slog.KindGroup {
+		attrs := a.Value.Group()
+		if len(attrs) == 0 {
+			return b
+		}
+		if a.Key != "" {
+			prefix += a.Key + "."
+			groups = append(slices.Clip(groups), a.Key)
+		}
+		for _, ga := range attrs {
+			b = h.appendAttr(b, prefix, groups, ga)
+		}
+		return b
+	}
+	if h.opts.ReplaceAttr != nil {
+		a = h.opts.ReplaceAttr(groups, a)
+		a.Value = a.Value.Resolve()
+	}
+	if a.Equal(slog.Attr{}) {
+		return b
+	}
+	return h.appendPair(b, prefix+a.Key, a.Value)
+}
+
+func (h *LogfmtHandler) appendPair(b []byte, key string, v slog.Value) []byte {
+	b = append(b, ' ')
+	b = appendLogfmtString(b, key)
+	b = append(b, '=')
+	return appendLogfmtValue(b, v)
+}
+
+func appendLogfmtValue(b []byte, v slog.Value) []byte {
+	switch v.Kind() {
+	case slog.KindString:
+		return appendLogfmtString(b, v.String())
+	case slog.KindInt64:
+		return strconv.AppendInt(b, v.Int64(), 10)
+	case slog.KindUint64:
+		return strconv.AppendUint(b, v.Uint64(), 10)
+	case slog.KindFloat64:
+		return strconv.AppendFloat(b, v.Float64(), 'g', -1, 64)
+	case slog.KindBool:
+		return strconv.AppendBool(b, v.Bool())
+	case slog.KindDuration:
+		return appendLogfmtString(b, v.Duration().String())
+	case slog.KindTime:
+		return v.Time().AppendFormat(b, time.RFC3339Nano)
+	}
+	switch x := v.Any().(type) {
+	case nil:
+		return append(b, "<nil>"...)
+	case error:
+		return appendLogfmtString(b, x.Error())
+	case []byte:
+		return appendLogfmtString(b, string(x))
+	case encoding.TextMarshaler:
+		text, err := x.MarshalText()
+		if err != nil {
+			return appendLogfmtString(b, "!ERROR:"+err.Error())
+		}
+		return appendLogfmtString(b, string(text))
+	}
+	return appendLogfmtString(b, fmt.Sprintf("%+v", v.Any()))
+}
+
+func appendLogfmtString(b []byte, s string) []byte {
+	if logfmtNeedsQuoting(s) {
+		return strconv.AppendQuote(b, s)
+	}
+	return append(b, s...)
+}
+
+func logfmtNeedsQuoting(s string) bool {
+	if s == "" {
+		return true
+	}
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c <= ' ' || c == '=' || c == '"' || c == '\\' || c == 0x7f {
+				return true
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError || unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			return true
+		}
+		i += size
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package slogx
+
+import (
+	"bytes"
+	"log/slog"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/slogtest"
+)
+
+func TestLogfmtHandler(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := slog.New(NewLogfmtHandler(buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	logger.With("app", "my app").WithGroup("http").Info("request done", "status", 200, "path", "/api", "q", `a="b"`)
+
+	expected := `level=INFO msg="request done" app="my app" http.status=200 http.path=/api http.q="a=\"b\""` + "\n"
+	if buf.String() != expected {
+		t.Fatalf("\n%s\nexpected\n%s", buf, expected)
+	}
+}
+
+func TestLogfmtHandlerSlogtest(t *testing.T) {
+	buf := new(bytes.Buffer)
+	err := slogtest.TestHandler(NewLogfmtHandler(buf, nil), func() []map[string]any {
+		var ms []map[string]any
+		for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+			m, err := parseLogfmt(line)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ms = append(ms, m)
+		}
+		return ms
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// parseLogfmt parses a logfmt line into maps nested by the dotted keys.
+func parseLogfmt(line string) (map[string]any, error) {
+	m := make(map[string]any)
+	for line != "" {
+		eq := strings.IndexByte(line, '=')
+		key := line[:eq]
+		line = line[eq+1:]
+		var val string
+		if strings.HasPrefix(line, `"`) {
+			q, err := strconv.QuotedPrefix(line)
+			if err != nil {
+				return nil, err
+			}
+			line = line[len(q):]
+			if val, err = strconv.Unquote(q); err != nil {
+				return nil, err
+			}
+		} else {
+			end := strings.IndexByte(line, ' ')
+			if end < 0 {
+				end = len(line)
+			}
+			val, line = line[:end], line[end:]
+		}
+		line = strings.TrimPrefix(line, " ")
+
+		dst := m
+		keys := strings.Split(key, ".")
+		for _, k := range keys[:len(keys)-1] {
+			sub, ok := dst[k].(map[string]any)
+			if !ok {
+				sub = make(map[string]any)
+				dst[k] = sub
+			}
+			dst = sub
+		}
+		dst[keys[len(keys)-1]] = val
+	}
+	return m, nil
+}
+
+// capWriter records the capacities of the written slices.
+type capWriter struct {
+	caps []int
+}
+
+func (w *capWriter) Write(p []byte) (int, error) {
+	w.caps = append(w.caps, cap(p))
+	return len(p), nil
+}
+
+func TestLogfmtHandlerBufferReuse(t *testing.T) {
+	w := new(capWriter)
+	logger := slog.New(NewLogfmtHandler(w, nil))
+	for range 100 {
+		logger.Info("x")
+	}
+	// the pooled buffers keep their capacity
+	for _, c := range w.caps {
+		if c != w.caps[0] {
+			t.Fatalf("capacities of the written buffers changed: %v", w.caps)
+		}
+	}
+}
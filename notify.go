@@ -0,0 +1,336 @@
+package slogx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+type (
+	// Notifier delivers a notification text to a chat.
+	Notifier interface {
+		Notify(ctx context.Context, text string) error
+	}
+
+	// SlackNotifier posts notifications to a Slack incoming webhook.
+	SlackNotifier struct {
+		WebhookURL string
+		Client     *http.Client // http.DefaultClient by default
+	}
+
+	// TelegramNotifier sends notifications with the Telegram bot API.
+	TelegramNotifier struct {
+		Token  string
+		ChatID string
+		APIURL string       // https://api.telegram.org by default
+		Client *http.Client // http.DefaultClient by default
+	}
+
+	// NotifyAttr is an attribute of NotifyData, with group names joined by dots.
+	NotifyAttr struct {
+		Key   string
+		Value string
+	}
+
+	// NotifyData is the data the notification template is executed with.
+	NotifyData struct {
+		Time       time.Time
+		Level      slog.Level
+		Message    string
+		Attrs      []NotifyAttr
+		Suppressed int // records with the same fingerprint throttled since the previous notification
+	}
+
+	// NotifyOptions configures NotifyHandler. Zero values select the defaults.
+	NotifyOptions struct {
+		Level    slog.Leveler       // slog.LevelError by default
+		Template *template.Template // DefaultNotifyTemplate by default
+		Throttle time.Duration      // minimum interval between notifications of a fingerprint, 10m by default
+		Timeout  time.Duration      // of a single notification, 10s by default
+
+		// Fingerprint identifies similar records, the level and the message by default
+		Fingerprint func(r slog.Record) string
+
+		Clock Clock // the system clock by default
+	}
+
+	// NotifyHandler sends critical records to a chat, for small teams that
+	// want to be paged on errors without a full alerting stack.
+	// Notifications are sent asynchronously; records of a fingerprint already
+	// notified within the throttle interval are only counted, and the count is
+	// reported with the next notification. It is meant to be combined with
+	// the main handler of the application by a fan-out handler.
+	NotifyHandler struct {
+		notifier Notifier
+		opts     NotifyOptions
+		state    attrState
+		shared   *notifyShared
+	}
+
+	notifyShared struct {
+		mu        sync.Mutex
+		throttled map[string]*notifyThrottle
+		sending   int       // notifications being sent
+		idle      sync.Cond // of mu, signaled when sending drops to zero
+		closed    bool
+		statsCounters
+
+		unregister func() // removes the handler from FlushAll
+		once       sync.Once
+	}
+
+	notifyThrottle struct {
+		last       time.Time
+		suppressed int
+	}
+)
+
+var (
+	_ slog.Handler  = (*NotifyHandler)(nil)
+	_ StatsProvider = (*NotifyHandler)(nil)
+)
+
+var errNotifyClosed = errors.New("slogx: notify handler is closed")
+
+// DefaultNotifyTemplate is the default template of notification texts.
+var DefaultNotifyTemplate = template.Must(template.New("notify").Parse(
+	`{{.Level}}: {{.Message}}{{range .Attrs}}
+{{.Key}}: {{.Value}}{{end}}{{if .Suppressed}}
+(+{{.Suppressed}} similar records suppressed){{end}}`))
+
+// NewNotifyHandler creates NotifyHandler sending notifications with the notifier
+func NewNotifyHandler(notifier Notifier, opts *NotifyOptions) *NotifyHandler {
+	h := &NotifyHandler{
+		notifier: notifier,
+		shared:   &notifyShared{throttled: make(map[string]*notifyThrottle)},
+	}
+	h.shared.idle.L = &h.shared.mu
+	if opts != nil {
+		h.opts = *opts
+	}
+	if h.opts.Level == nil {
+		h.opts.Level = slog.LevelError
+	}
+	if h.opts.Template == nil {
+		h.opts.Template = DefaultNotifyTemplate
+	}
+	if h.opts.Throttle <= 0 {
+		h.opts.Throttle = 10 * time.Minute
+	}
+	if h.opts.Timeout <= 0 {
+		h.opts.Timeout = 10 * time.Second
+	}
+	if h.opts.Fingerprint == nil {
+		h.opts.Fingerprint = func(r slog.Record) string {
+			return r.Level.String() + " " + r.Message
+		}
+	}
+	h.opts.Clock = clockOrSystem(h.opts.Clock)
+	h.shared.unregister = RegisterFlusher(h)
+	return h
+}
+
+func (h *NotifyHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.opts.Level.Level()
+}
+
+func (h *NotifyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.state = h.state.withAttrs(attrs)
+	return &h2
+}
+
+func (h *NotifyHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.state = h.state.withGroup(name)
+	return &h2
+}
+
+func (h *NotifyHandler) Handle(_ context.Context, r slog.Record) error {
+	suppressed, ok := h.throttle(h.opts.Fingerprint(r))
+	if !ok {
+		return nil
+	}
+	data := NotifyData{
+		Time:       r.Time,
+		Level:      r.Level,
+		Message:    r.Message,
+		Attrs:      appendNotifyAttrs(nil, "", h.state.attrs(r, nil)),
+		Suppressed: suppressed,
+	}
+	text := new(strings.Builder)
+	if err := h.opts.Template.Execute(text, data); err != nil {
+		return err
+	}
+
+	if !h.shared.start() {
+		h.shared.dropped.Add(1)
+		return errNotifyClosed
+	}
+	go func() {
+		defer h.shared.done()
+		ctx, cancel := context.WithTimeout(context.Background(), h.opts.Timeout)
+		defer cancel()
+		if err := h.notifier.Notify(ctx, text.String()); err != nil {
+			h.shared.dropped.Add(1)
+			h.shared.setError(redactURL(err), h.opts.Clock.Now())
+			return
+		}
+		h.shared.handled.Add(1)
+	}()
+	return nil
+}
+
+// Flush waits for the notifications being sent.
+func (h *NotifyHandler) Flush() error {
+	h.shared.mu.Lock()
+	for h.shared.sending > 0 {
+		h.shared.idle.Wait()
+	}
+	h.shared.mu.Unlock()
+	return nil
+}
+
+// Close waits for the notifications being sent and removes the handler from
+// FlushAll. Records handled afterwards are dropped with an error.
+func (h *NotifyHandler) Close() error {
+	h.shared.mu.Lock()
+	h.shared.closed = true
+	h.shared.mu.Unlock()
+	h.shared.once.Do(h.shared.unregister)
+	return h.Flush()
+}
+
+// start counts a notification being sent, false if the handler is closed.
+func (s *notifyShared) start() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	s.sending++
+	return true
+}
+
+// done counts a notification sent, waking Flush when none is left.
+func (s *notifyShared) done() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sending--; s.sending == 0 {
+		s.idle.Broadcast()
+	}
+}
+
+// Stats returns the delivery counters of the handler. Throttled records are not counted.
+func (h *NotifyHandler) Stats() Stats {
+	return h.shared.Stats()
+}
+
+func (h *NotifyHandler) sharedState() any {
+	return h.shared
+}
+
+// throttle reports whether a notification of the fingerprint is to be sent now
+// and the number of its records suppressed since the previous one.
+func (h *NotifyHandler) throttle(fingerprint string) (int, bool) {
+	now := h.opts.Clock.Now()
+
+	h.shared.mu.Lock()
+	defer h.shared.mu.Unlock()
+
+	t, ok := h.shared.throttled[fingerprint]
+	if ok && now.Sub(t.last) < h.opts.Throttle {
+		t.suppressed++
+		return 0, false
+	}
+	if !ok {
+		if len(h.shared.throttled) >= 1000 {
+			for k, t := range h.shared.throttled {
+				if now.Sub(t.last) >= h.opts.Throttle {
+					delete(h.shared.throttled, k)
+				}
+			}
+		}
+		t = new(notifyThrottle)
+		h.shared.throttled[fingerprint] = t
+	}
+	suppressed := t.suppressed
+	t.last, t.suppressed = now, 0
+	return suppressed, true
+}
+
+func appendNotifyAttrs(xs []NotifyAttr, prefix string, attrs []slog.Attr) []NotifyAttr {
+	for _, a := range attrs {
+		if a.Value.Kind() == slog.KindGroup {
+			xs = appendNotifyAttrs(xs, prefix+a.Key+".", a.Value.Group())
+			continue
+		}
+		xs = append(xs, NotifyAttr{Key: prefix + a.Key, Value: a.Value.String()})
+	}
+	return xs
+}
+
+func (n SlackNotifier) Notify(ctx context.Context, text string) error {
+	return postNotification(ctx, n.Client, n.WebhookURL, map[string]string{"text": text})
+}
+
+func (n TelegramNotifier) Notify(ctx context.Context, text string) error {
+	apiURL := n.APIURL
+	if apiURL == "" {
+		apiURL = "https://api.telegram.org"
+	}
+	return postNotification(ctx, n.Client, apiURL+"/bot"+n.Token+"/sendMessage", map[string]string{
+		"chat_id": n.ChatID,
+		"text":    text,
+	})
+}
+
+// redactURL returns err with the URL of its *url.Error reduced to the scheme
+// and the host, since the webhook URL of Slack and the bot API URL of
+// Telegram carry the secret the errors are not to expose in Stats.
+func redactURL(err error) error {
+	var ue *url.Error
+	if errors.As(err, &ue) {
+		if u, perr := url.Parse(ue.URL); perr == nil && u.Host != "" {
+			ue.URL = u.Scheme + "://" + u.Host
+		} else {
+			ue.URL = "<redacted>"
+		}
+	}
+	return err
+}
+
+func postNotification(ctx context.Context, client *http.Client, url string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("notify: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package slogx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"text/template"
+	"time"
+)
+
+type notifyServer struct {
+	mu    sync.Mutex
+	texts []string
+}
+
+func (s *notifyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var payload map[string]string
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	s.texts = append(s.texts, payload["text"])
+	s.mu.Unlock()
+}
+
+func TestNotifyHandler(t *testing.T) {
+	srv := new(notifyServer)
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	h := NewNotifyHandler(SlackNotifier{WebhookURL: ts.URL}, &NotifyOptions{
+		Clock: ClockFunc(func() time.Time { return now }),
+	})
+	logger := slog.New(h)
+
+	logger.Info("ignored")
+	logger.Error("failed", "n", 1)
+	logger.Error("failed", "n", 2)
+	logger.Error("failed", "n", 3)
+	if err := h.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(11 * time.Minute)
+	logger.With("app", "api").WithGroup("req").Error("failed", "n", 4)
+	if err := h.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"ERROR: failed\nn: 1",
+		"ERROR: failed\napp: api\nreq.n: 4\n(+2 similar records suppressed)",
+	}
+	if strings.Join(srv.texts, "\n---\n") != strings.Join(expected, "\n---\n") {
+		t.Fatalf("\n%q\nexpected\n%q", srv.texts, expected)
+	}
+	if s := h.Stats(); s.Handled != 2 || s.Dropped != 0 || s.LastError != nil {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+}
+
+func TestNotifyHandlerTemplate(t *testing.T) {
+	srv := new(notifyServer)
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	h := NewNotifyHandler(TelegramNotifier{Token: "token", ChatID: "chat", APIURL: ts.URL}, &NotifyOptions{
+		Level:    slog.LevelWarn,
+		Template: template.Must(template.New("").Parse(`[{{.Level}}] {{.Message}}{{range .Attrs}} {{.Key}}={{.Value}}{{end}}`)),
+	})
+	slog.New(h).Warn("disk full", "path", "/var", "free", 0)
+	if err := h.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "[WARN] disk full path=/var free=0"
+	if len(srv.texts) != 1 || srv.texts[0] != expected {
+		t.Fatalf("\n%q\nexpected\n%q", srv.texts, expected)
+	}
+}
+
+func TestNotifyHandlerError(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	ts.Close()
+
+	h := NewNotifyHandler(TelegramNotifier{Token: "123:secret", ChatID: "chat", APIURL: ts.URL}, nil)
+	slog.New(h).Error("failed")
+	if err := h.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	s := h.Stats()
+	if s.Handled != 0 || s.Dropped != 1 || s.LastError == nil {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+	if msg := s.LastError.Error(); strings.Contains(msg, "secret") || !strings.Contains(msg, ts.URL) {
+		t.Fatalf("unexpected error %q", msg)
+	}
+}
+
+func TestNotifyHandlerFlushConcurrent(t *testing.T) {
+	var sent atomic.Int64
+	h := NewNotifyHandler(notifyFunc(func(context.Context, string) error {
+		sent.Add(1)
+		return nil
+	}), nil)
+	logger := slog.New(h)
+
+	var wg sync.WaitGroup
+	for g := range 4 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := range 50 {
+				logger.Error(fmt.Sprintf("failed %d-%d", g, i))
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				_ = h.Flush()
+			}
+		}()
+	}
+	wg.Wait()
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := sent.Load(); n != 200 {
+		t.Fatalf("sent %d notifications, expected 200", n)
+	}
+
+	if err := h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelError, "after close", 0)); err == nil {
+		t.Fatal("no error after Close")
+	}
+	if s := h.Stats(); s.Handled != 200 || s.Dropped != 1 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+}
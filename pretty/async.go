@@ -0,0 +1,169 @@
+package pretty
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/fpawel/slogx"
+)
+
+// asyncWriter writes the lines queued by Handle in a background goroutine
+type asyncWriter struct {
+	mu     sync.RWMutex // held for reading while queueing, for writing while closing
+	queue  chan asyncLine
+	closed bool
+	done   chan struct{} // closed when the goroutine returns
+
+	unregister func() // removes the output from slogx.FlushAll
+
+	errMu sync.Mutex
+	err   error // of the last write failed since Flush
+}
+
+type asyncLine struct {
+	buf     *[]byte       // from bufPool, returned by the goroutine
+	flushed chan struct{} // closed when the lines queued before are written, instead of buf
+}
+
+// WithAsync sets writing the records to the output of the handler in a
+// background goroutine, so Handle does not wait for the terminal; the writer
+// of WithErrorWriter has a goroutine of its own. Up to size records are
+// queued; Handle waits when the queue is full. Flush waits until
+// the queued records are written and Close stops the goroutine, after which
+// the records are written synchronously. The handler is flushed by
+// slogx.FlushAll until closed. WithOutput and WithErrorWriter after
+// WithAsync write synchronously.
+//
+//	h := pretty.NewHandler().WithAsync(1024)
+//	defer h.Close()
+func (h Handler) WithAsync(size int) Handler {
+	h.out = h.output().withAsync(size)
+	if h.errOut != nil {
+		h.errOut = h.errOut.withAsync(size)
+	}
+	return h
+}
+
+// withAsync returns the output writing to w in a background goroutine
+func (o *output) withAsync(size int) *output {
+	a := &asyncWriter{queue: make(chan asyncLine, max(size, 1)), done: make(chan struct{})}
+	x := &output{mu: o.mu, w: o.w, depth: o.depth, async: a}
+	a.unregister = slogx.RegisterFlusher(x)
+	go a.run(x)
+	return x
+}
+
+var _ slogx.Flusher = Handler{}
+
+// Flush writes the counters of WithCollapseRepeats, waits until the queued
+// records are written, flushes the writers and the Tee implementing
+// slogx.Flusher and returns the errors, of the queued records the one of the
+// last failed write since the previous Flush
+func (h Handler) Flush() error {
+	var errs []error
+	for _, o := range h.outputs() {
+		errs = append(errs, o.flush())
+	}
+	if f, ok := h.Tee.(slogx.Flusher); ok {
+		errs = append(errs, f.Flush())
+	}
+	return errors.Join(errs...)
+}
+
+// Close flushes the handler as Flush, stops the background goroutine of
+// WithAsync and the timer of WithCollapseRepeats and closes the Tee as
+// slogx.Close, to be called on shutdown. The writers are not closed; the
+// records handled after Close are written synchronously.
+func (h Handler) Close() error {
+	var errs []error
+	for _, o := range h.outputs() {
+		errs = append(errs, o.close())
+	}
+	if h.Tee != nil {
+		errs = append(errs, slogx.Close(h.Tee))
+	}
+	return errors.Join(errs...)
+}
+
+// Flush flushes the output as Handler.Flush, for slogx.FlushAll
+func (o *output) Flush() error {
+	return o.flush()
+}
+
+func (o *output) flush() error {
+	errs := []error{o.flushRepeats()}
+	if o.async != nil {
+		errs = append(errs, o.async.flush())
+	}
+	if f, ok := o.w.(slogx.Flusher); ok {
+		errs = append(errs, f.Flush())
+	}
+	return errors.Join(errs...)
+}
+
+func (o *output) close() error {
+	errs := []error{o.closeRepeats()}
+	if o.async != nil {
+		errs = append(errs, o.async.close())
+	}
+	if f, ok := o.w.(slogx.Flusher); ok {
+		errs = append(errs, f.Flush())
+	}
+	return errors.Join(errs...)
+}
+
+func (a *asyncWriter) flush() error {
+	a.mu.RLock()
+	if !a.closed {
+		flushed := make(chan struct{})
+		a.queue <- asyncLine{flushed: flushed}
+		a.mu.RUnlock()
+		<-flushed
+	} else {
+		a.mu.RUnlock()
+	}
+	a.errMu.Lock()
+	defer a.errMu.Unlock()
+	err := a.err
+	a.err = nil
+	return err
+}
+
+func (a *asyncWriter) close() error {
+	a.mu.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.queue)
+		a.unregister()
+	}
+	a.mu.Unlock()
+	<-a.done
+	return a.flush()
+}
+
+// enqueue queues the buffer, false if the writer is closed
+func (a *asyncWriter) enqueue(buf *[]byte) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		return false
+	}
+	a.queue <- asyncLine{buf: buf}
+	return true
+}
+
+func (a *asyncWriter) run(o *output) {
+	defer close(a.done)
+	for line := range a.queue {
+		if line.flushed != nil {
+			close(line.flushed)
+			continue
+		}
+		if err := o.writeSync(*line.buf); err != nil {
+			a.errMu.Lock()
+			a.err = err
+			a.errMu.Unlock()
+		}
+		putBuf(line.buf)
+	}
+}
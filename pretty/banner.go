@@ -0,0 +1,31 @@
+package pretty
+
+import "strings"
+
+// bannerWidth is the width of the lines of Banner
+const bannerWidth = 80
+
+// Banner writes the line of the title centered between rules to the output of
+// the handler, marking a section of the output, e.g. a test phase or a stage
+// of a command:
+//
+//	──────────────────────────────── migrations ────────────────────────────────
+func (h Handler) Banner(title string) error {
+	out := h.output()
+	theme, depth := h.colors(out)
+	if err := out.flushRepeats(); err != nil {
+		return err
+	}
+	left, right := bannerWidth, 0
+	if title != "" {
+		title = " " + title + " "
+		rules := max(bannerWidth-displayWidth(title), 4)
+		left, right = rules/2, rules-rules/2
+	}
+	bp := bufPool.Get().(*[]byte)
+	b := theme.Dim.appendTo((*bp)[:0], depth, strings.Repeat("─", left))
+	b = theme.Message.appendTo(b, depth, title)
+	b = theme.Dim.appendTo(b, depth, strings.Repeat("─", right))
+	*bp = append(b, '\n')
+	return out.write(bp)
+}
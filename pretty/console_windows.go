@@ -0,0 +1,40 @@
+//go:build windows
+
+package pretty
+
+import (
+	"io"
+	"os"
+
+	"github.com/mattn/go-colorable"
+	"golang.org/x/sys/windows"
+)
+
+// consoleOutput enables the virtual terminal processing of the console of w, so
+// that it interprets the escape sequences of colors, or translates them by
+// go-colorable on consoles not supporting it
+func consoleOutput(w io.Writer) io.Writer {
+	f, ok := w.(*os.File)
+	if !ok || !isTerminal(f) {
+		return w
+	}
+	h := windows.Handle(f.Fd())
+	var mode uint32
+	if err := windows.GetConsoleMode(h, &mode); err != nil {
+		return w
+	}
+	if windows.SetConsoleMode(h, mode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING) == nil {
+		return w
+	}
+	return colorableFile{colorable.NewColorable(f), f.Fd()}
+}
+
+// colorableFile keeps the descriptor of the console for isTerminal
+type colorableFile struct {
+	io.Writer
+	fd uintptr
+}
+
+func (f colorableFile) Fd() uintptr {
+	return f.fd
+}
@@ -0,0 +1,238 @@
+package pretty
+
+import (
+	"log/slog"
+	"math"
+	"slices"
+	"strconv"
+	"unicode/utf8"
+)
+
+// appendAttrsFast appends the separator and the attributes of the handler and
+// the record directly, without the intermediate object, if they are all of
+// scalar kinds with distinct keys and no option changes their printing. It
+// returns false if the attributes need the general path, having appended nothing.
+// The output is the same as of the general path.
+func (h Handler) appendAttrsFast(p *renderer, r Record, sep string) bool {
+	if h.SlogOpts.ReplaceAttr != nil || h.SortedKeys || h.Vertical || h.JSONIndent != "" || h.Plain != nil ||
+		len(h.HiddenKeys) != 0 || len(h.DimKeys) != 0 || p.maxItems != 0 {
+		return false
+	}
+	n := len(h.Attrs) + r.NumAttrs()
+	if n == 0 {
+		return true
+	}
+	ok := true
+	seen := 0
+	check := func(a Attr) bool {
+		ok = fastValue(a.Value) && utf8.ValidString(a.Key) && !h.fastKeySeen(r, a.Key, seen) &&
+			!slices.Contains(h.Groups, a.Key)
+		seen++
+		return ok
+	}
+	for _, a := range h.Attrs {
+		if !check(a) {
+			return false
+		}
+	}
+	r.Attrs(check)
+	if !ok {
+		return false
+	}
+
+	// the groups deeper than the last one with attributes are omitted
+	last := len(h.Groups)
+	if r.NumAttrs() == 0 {
+		for last > 0 && len(h.groupAttrs(last)) == 0 {
+			last--
+		}
+	}
+	for _, g := range h.Groups[:last] {
+		if !utf8.ValidString(g) {
+			return false
+		}
+	}
+
+	start := len(p.b)
+	p.b = append(p.b, sep...)
+	punct := p.theme.Attrs
+	p.color(punct, "{")
+	i := 0
+	member := func(a Attr) bool {
+		if i > 0 {
+			p.color(punct, ",")
+		}
+		i++
+		p.appendKey(punct, a.Key)
+		ok = p.appendScalar(a.Value)
+		return ok
+	}
+	for d := 0; d <= last; d++ {
+		if d > 0 {
+			if i > 0 {
+				p.color(punct, ",")
+			}
+			p.appendKey(punct, h.Groups[d-1])
+			p.color(punct, "{")
+			i = 0
+		}
+		for _, a := range h.groupAttrs(d) {
+			if !member(a) {
+				p.b = p.b[:start]
+				return false
+			}
+		}
+	}
+	r.Attrs(member)
+	if !ok {
+		p.b = p.b[:start]
+		return false
+	}
+	for range last + 1 {
+		p.color(punct, "}")
+	}
+	return true
+}
+
+// fastValue reports whether the value is printed by appendScalar
+func fastValue(v slog.Value) bool {
+	switch v.Kind() {
+	case slog.KindString:
+		return utf8.ValidString(v.String()) // encoding/json versions differ in replacing invalid runes
+	case slog.KindInt64, slog.KindUint64, slog.KindBool:
+		return true
+	case slog.KindFloat64:
+		f := v.Float64()
+		return !math.IsNaN(f) && !math.IsInf(f, 0)
+	}
+	return false
+}
+
+// fastKeySeen reports whether one of the first n attributes of the handler
+// and the record has the key
+func (h Handler) fastKeySeen(r Record, key string, n int) bool {
+	for _, a := range h.Attrs {
+		if n == 0 {
+			return false
+		}
+		n--
+		if a.Key == key {
+			return true
+		}
+	}
+	seen := false
+	r.Attrs(func(a Attr) bool {
+		if n == 0 {
+			return false
+		}
+		n--
+		seen = a.Key == key
+		return !seen
+	})
+	return seen
+}
+
+// appendKey appends the JSON key of the member colored
+func (p *renderer) appendKey(c Color, key string) {
+	p.colorStart(c)
+	p.b = appendJSONString(p.b, key)
+	p.b = append(p.b, ':')
+	p.colorEnd(c)
+}
+
+// appendScalar appends the value colored as the general path does
+func (p *renderer) appendScalar(v slog.Value) bool {
+	var c Color
+	switch v.Kind() {
+	case slog.KindString:
+		c = p.theme.String
+	case slog.KindBool:
+		c = p.theme.Bool
+	default:
+		c = p.theme.Number
+	}
+	if !c.set {
+		c = p.theme.Attrs
+	}
+	p.colorStart(c)
+	switch v.Kind() {
+	case slog.KindString:
+		p.b = appendJSONString(p.b, v.String())
+	case slog.KindInt64:
+		p.b = strconv.AppendInt(p.b, v.Int64(), 10)
+	case slog.KindUint64:
+		p.b = strconv.AppendUint(p.b, v.Uint64(), 10)
+	case slog.KindBool:
+		p.b = strconv.AppendBool(p.b, v.Bool())
+	case slog.KindFloat64:
+		p.b = appendJSONFloat(p.b, v.Float64())
+	default:
+		return false
+	}
+	p.colorEnd(c)
+	return true
+}
+
+// appendJSONFloat appends f as encoding/json does
+func appendJSONFloat(b []byte, f float64) []byte {
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+	b = strconv.AppendFloat(b, f, format, -1, 64)
+	if format == 'e' {
+		// clean up e-09 to e-9
+		n := len(b)
+		if n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+			b[n-2] = b[n-1]
+			b = b[:n-1]
+		}
+	}
+	return b
+}
+
+// appendJSONString appends the valid UTF-8 s quoted as encoding/json does, escaping HTML characters
+func appendJSONString(b []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && c != '<' && c != '>' && c != '&' {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				b = append(b, '\\', c)
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			case '\b':
+				b = append(b, '\\', 'b')
+			case '\f':
+				b = append(b, '\\', 'f')
+			default:
+				b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == '\u2028' || r == '\u2029' {
+			b = append(b, s[start:i]...)
+			b = append(b, '\\', 'u', '2', '0', '2', hex[r&0xf])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"')
+}
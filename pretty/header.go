@@ -0,0 +1,56 @@
+package pretty
+
+import (
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strconv"
+	"sync"
+)
+
+// processHeader is the text of the process metadata line
+var processHeader = sync.OnceValue(func() string {
+	s := "▶ " + filepath.Base(os.Args[0])
+	if info, ok := debug.ReadBuildInfo(); ok {
+		s += " " + info.Main.Version
+		for _, x := range info.Settings {
+			if x.Key == "vcs.revision" && len(x.Value) >= 12 {
+				s += " " + x.Value[:12]
+			}
+		}
+		s += " " + info.GoVersion
+	}
+	s += " pid=" + strconv.Itoa(os.Getpid())
+	if host, err := os.Hostname(); err == nil {
+		s += " host=" + host
+	}
+	return s
+})
+
+// WithProcessHeader sets writing the line of the process metadata once before
+// the first record of the output, see WriteProcessHeader
+func (h Handler) WithProcessHeader(v bool) Handler {
+	h.ProcessHeader = v
+	return h
+}
+
+// WriteProcessHeader writes the line of the program name, version, build
+// revision, Go version, PID and hostname to the output of the handler, so the
+// log session is self-describing:
+//
+//	▶ server v1.4.0 3f2a9c1d7e0b go1.22.1 pid=4242 host=dev
+//
+// The line is not written again before the next record of WithProcessHeader.
+func (h Handler) WriteProcessHeader() error {
+	out := h.output()
+	out.headerDone.Store(true)
+	return h.writeProcessHeader(out)
+}
+
+// writeProcessHeader writes the line of the process metadata to the output
+func (h Handler) writeProcessHeader(out *output) error {
+	theme, depth := h.colors(out)
+	bp := bufPool.Get().(*[]byte)
+	*bp = append(theme.Dim.appendTo((*bp)[:0], depth, processHeader()), '\n')
+	return out.write(bp)
+}
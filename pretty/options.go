@@ -0,0 +1,133 @@
+package pretty
+
+import (
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/fpawel/slogx"
+)
+
+// Options configures the Handler created by New in one call, e.g. from a
+// config struct. The zero Options is the configuration of NewHandler.
+type Options struct {
+	Output         io.Writer    // os.Stderr by default
+	ErrorOutput    io.Writer    // of the records of ErrorLevel and above if not nil
+	ErrorLevel     slog.Leveler // slog.LevelWarn by default
+	Level          slog.Leveler // slog.LevelDebug by default
+	AddSource      bool
+	SourceStyle    SourceStyle
+	OmitSourceFunc bool
+	ReplaceAttr    func(groups []string, a Attr) Attr
+
+	TimeLayout      string // TimeLayoutSeconds by default
+	OmitTime        bool   // do not print the time
+	TimeMode        TimeMode
+	UTC             bool
+	RepeatedTime    RepeatedTimeStyle
+	CollapseRepeats time.Duration
+	MessageGutter   string
+	Clock           slogx.Clock
+
+	JSONIndent    string
+	Vertical      bool
+	Plain         *PlainFormat
+	SortedKeys    bool
+	MaxAttrs      int
+	MaxGroupDepth int
+	DimKeys       []string
+	HiddenKeys    []string
+	Formats       Formats
+
+	Theme         *Theme
+	ColorDepth    ColorDepth
+	Color         *bool
+	LevelNames    map[Level]string
+	LevelStyle    LevelStyle
+	Icons         bool
+	GoroutineID   bool
+	ContextAttrs  bool
+	ProcessHeader bool
+
+	Tee   slog.Handler // also handles the records if not nil
+	Async int          // queue size of WithAsync if positive
+}
+
+// New creates Handler configured by the options
+func New(opts Options) Handler {
+	h := NewHandler()
+	if opts.Output != nil {
+		h.out = newOutput(opts.Output)
+	}
+	if opts.ErrorOutput != nil {
+		h.errOut = newOutput(opts.ErrorOutput)
+	}
+	h.ErrorLevel = opts.ErrorLevel
+	if opts.Level != nil {
+		h.SlogOpts.Level = opts.Level
+	}
+	h.SlogOpts.AddSource = opts.AddSource
+	h.SourceStyle = opts.SourceStyle
+	h.OmitSourceFunc = opts.OmitSourceFunc
+	h.SlogOpts.ReplaceAttr = opts.ReplaceAttr
+	switch {
+	case opts.OmitTime:
+		h.TimeLayout = ""
+	case opts.TimeLayout != "":
+		h.TimeLayout = opts.TimeLayout
+	}
+	h.Clock = opts.Clock
+	h.UTC = opts.UTC
+	h.RepeatedTime = opts.RepeatedTime
+	h.CollapseRepeats = opts.CollapseRepeats
+	h.MessageGutter = opts.MessageGutter
+	if opts.TimeMode != TimeModeAbsolute {
+		h = h.WithTimeMode(opts.TimeMode)
+	}
+	h.JSONIndent = opts.JSONIndent
+	h.Vertical = opts.Vertical
+	h.Plain = opts.Plain
+	h.SortedKeys = opts.SortedKeys
+	h.MaxAttrs = opts.MaxAttrs
+	h.MaxGroupDepth = opts.MaxGroupDepth
+	h.DimKeys = opts.DimKeys
+	h.HiddenKeys = opts.HiddenKeys
+	h.Formats = opts.Formats
+	h.Theme = opts.Theme
+	h.ColorDepth = opts.ColorDepth
+	h.Color = opts.Color
+	h.LevelNames = opts.LevelNames
+	h.LevelStyle = opts.LevelStyle
+	h.Icons = opts.Icons
+	h.GoroutineID = opts.GoroutineID
+	h.ContextAttrs = opts.ContextAttrs
+	h.ProcessHeader = opts.ProcessHeader
+	h.Tee = opts.Tee
+	if opts.Async > 0 {
+		h = h.WithAsync(opts.Async)
+	}
+	return h
+}
+
+// PresetCompact creates Handler printing the records on single lines with the
+// short level names, only the first attributes and collection items, and the
+// time of seconds
+func PresetCompact() Handler {
+	return NewHandler().
+		WithTimeLayout(TimeLayoutSeconds).
+		WithLevelStyle(LevelStyleShort3).
+		WithMaxAttrs(8).
+		WithSourceFunc(false)
+}
+
+// PresetExpanded creates Handler printing the attributes on their own lines,
+// the multi-line messages as stack traces behind a gutter, the dates and times
+// of microseconds and the source locations in the module
+func PresetExpanded() Handler {
+	return NewHandler().
+		WithTimeLayout(time.DateTime + ".000000").
+		WithVertical(true).
+		WithMessageGutter("│ ").
+		WithAddSource(true).
+		WithSourceFormat(SourceStyleModule)
+}
@@ -0,0 +1,137 @@
+package pretty
+
+import (
+	"encoding"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// PlainFormat is how the attributes are printed as key=value pairs instead of
+// JSON, the keys of the groups joined by dots, e.g. req.method=GET req.status=200.
+// The values of other types than strings, errors and Formats are printed as JSON.
+type PlainFormat struct {
+	KeyValueSep string  // "=" if empty
+	PairSep     string  // " " if empty
+	Quoting     Quoting // of the strings
+}
+
+// Quoting is when the strings of PlainFormat are quoted
+type Quoting int
+
+const (
+	QuotingWhenNeeded Quoting = iota // empty strings and the ones with spaces, quotes, separators or non-printable characters
+	QuotingAlways
+	QuotingNever
+)
+
+// WithPlain sets printing the attributes on the line of the message as
+// key=value pairs of the format instead of JSON, e.g. to match grep patterns
+func (h Handler) WithPlain(f PlainFormat) Handler {
+	h.Plain = &f
+	return h
+}
+
+// withDefaults returns the format with the empty separators set to the default ones
+func (f PlainFormat) withDefaults() PlainFormat {
+	if f.KeyValueSep == "" {
+		f.KeyValueSep = "="
+	}
+	if f.PairSep == "" {
+		f.PairSep = " "
+	}
+	return f
+}
+
+// appendPlainAttrs appends the separator and the attributes of the handler and
+// the record as the pairs of the plain format, nothing if there are no attributes
+func (h Handler) appendPlainAttrs(p *renderer, r Record, sep string) error {
+	attrs, more := h.printedAttrs(r)
+	o := newObject(attrs, h.SortedKeys)
+	if len(o) == 0 {
+		return nil
+	}
+	p.b = append(p.b, sep...)
+	n := 0
+	if err := p.plainObject(h.Plain.withDefaults(), o, "", &n); err != nil {
+		return err
+	}
+	p.b = append(p.b, more...)
+	return nil
+}
+
+// plainObject appends the members of the object as pairs, the keys prefixed,
+// counting the pairs appended
+func (p *renderer) plainObject(f PlainFormat, o object, prefix string, n *int) error {
+	for _, m := range o {
+		key := prefix + m.key
+		if x, ok := m.value.(object); ok {
+			if err := p.plainObject(f, x, key+".", n); err != nil {
+				return err
+			}
+			continue
+		}
+		if *n > 0 {
+			p.b = append(p.b, f.PairSep...)
+		}
+		*n++
+		if err := p.plainPair(f, key, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// plainPair appends the key and the value of m, dimmed if the key of m is one of dimKeys
+func (p *renderer) plainPair(f PlainFormat, key string, m member) error {
+	if slices.Contains(p.dimKeys, m.key) {
+		theme := p.theme
+		p.theme = theme.dimmed()
+		defer func() { p.theme = theme }()
+	}
+	if f.Quoting != QuotingNever {
+		key = f.quote(key, QuotingWhenNeeded)
+	}
+	p.color(p.theme.Attrs, key+f.KeyValueSep)
+	c := p.theme.valueColor(m.value)
+	switch v := m.value.(type) {
+	case string:
+		p.color(c, f.quote(v, f.Quoting))
+		return nil
+	case error:
+		p.color(c, f.quote(v.Error(), f.Quoting))
+		return nil
+	case collapsedGroup:
+		p.color(c, v.String())
+		return nil
+	}
+	if s, ok := p.formats.format(m.value); ok {
+		p.color(c, f.quote(s, f.Quoting))
+		return nil
+	}
+	if v, ok := m.value.(encoding.TextMarshaler); ok {
+		text, err := v.MarshalText()
+		if err != nil {
+			return err
+		}
+		p.color(c, f.quote(string(text), f.Quoting))
+		return nil
+	}
+	return p.value(m.value, 0)
+}
+
+// quote returns s quoted by the quoting
+func (f PlainFormat) quote(s string, q Quoting) string {
+	switch q {
+	case QuotingAlways:
+		return strconv.Quote(s)
+	case QuotingNever:
+		return s
+	}
+	if s == "" || strings.ContainsAny(s, ` "=`) || strings.Contains(s, f.KeyValueSep) ||
+		strings.Contains(s, f.PairSep) || strings.ContainsFunc(s, func(r rune) bool { return !unicode.IsPrint(r) }) {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -1,48 +1,111 @@
 package pretty
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"github.com/fatih/color"
+	"github.com/fpawel/slogx"
+	"github.com/fpawel/slogx/slogctx"
 	"io"
-	"log"
 	"log/slog"
+	"maps"
 	"os"
-	"path/filepath"
 	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type (
 	Handler struct {
 		SlogOpts
-		Logger     *log.Logger
-		TimeLayout string // by default, do not display the time locally
-		Attrs      []Attr
-		Groups     []string
+		out             *output           // os.Stderr if nil
+		errOut          *output           // of the records of ErrorLevel and above if not nil
+		ErrorLevel      slog.Leveler      // of the records written by the writer of WithErrorWriter, slog.LevelWarn if nil
+		TimeLayout      string            // by default, do not display the time locally
+		TimeMode        TimeMode          // what the time column shows, see WithTimeMode
+		UTC             bool              // prints the times in UTC instead of the local time
+		RepeatedTime    RepeatedTimeStyle // how the time equal to the time of the previous record is displayed
+		CollapseRepeats time.Duration     // collapses the consecutive repeated records if positive, see WithCollapseRepeats
+		MessageGutter   string            // prefix of the message lines following the first one
+		Plain           *PlainFormat      // prints the attributes as key=value pairs instead of JSON if not nil
+		GoroutineID     bool              // prints the ID of the goroutine calling the logger after the time
+		ContextAttrs    bool              // prints the slogctx fields of the context dimmed after the message
+		ProcessHeader   bool              // writes the line of the process metadata before the first record of the output
+		times           *timeState        // of the relative time modes
+		Clock           slogx.Clock       // time source of records without time, printed without time if nil, and of the relative time modes
+		Attrs           []Attr
+		Groups          []string
+		groupStarts     []int            // indexes of the first Attrs of each of the Groups, all of them in the last group if the lengths differ
+		JSONIndent      string           // indents the attributes JSON on the lines following the message if not empty
+		Vertical        bool             // prints each attribute on its own line below the message
+		SortedKeys      bool             // prints the attributes sorted by key instead of in the order they were added
+		Theme           *Theme           // colors of the record parts, ThemeDark by default
+		ColorDepth      ColorDepth       // colors the terminal supports, detected by DetectColorDepth by default
+		Color           *bool            // prints colors or not regardless of ColorEnabled if not nil
+		LevelNames      map[Level]string // names of the levels in addition to and instead of the default ones
+		LevelStyle      LevelStyle       // how the level names are displayed
+		Icons           bool             // prefixes the records with the level icons of the theme
+		MaxAttrs        int              // prints only the first attributes of records if positive, see MaxAttrsEnv
+		DimKeys         []string         // keys of the attributes printed in the Dim color of the theme
+		HiddenKeys      []string         // keys of the attributes not printed
+		Formats         Formats          // how the durations, times and byte slices are printed
+		MaxGroupDepth   int              // groups nested deeper are printed collapsed if positive
+		Tee             slog.Handler     // also handles the records if not nil, see WithTee
+		SourceStyle     SourceStyle      // how the paths of the source files are displayed
+		OmitSourceFunc  bool             // does not display the function names in the source locations
 	}
 	Record      = slog.Record
 	Attr        = slog.Attr
 	SlogOpts    = slog.HandlerOptions
 	SlogHandler = slog.Handler
 	marshalFunc func(interface{}) ([]byte, error)
-	Level       = slog.Level
-	levelInfo   struct {
-		text      string
-		colorFunc func(format string, a ...interface{}) string
-	}
+
+	// LevelStyle is how the level names are displayed
+	LevelStyle int
+	Level      = slog.Level
+)
+
+const (
+	LevelStyleFull   LevelStyle = iota // the whole name, e.g. "INFO"
+	LevelStyleShort3                   // three characters, e.g. "INF"
+	LevelStyleChar                     // the first character, e.g. "I"
+)
+
+// MaxAttrsEnv is the environment variable overriding Handler.MaxAttrs for a run,
+// e.g. SLOGX_PRETTY_MAX_ATTRS=0 to print all the attributes
+const MaxAttrsEnv = "SLOGX_PRETTY_MAX_ATTRS"
+
+// Custom levels named by default
+const (
+	LevelTrace Level = -8
+	LevelFatal Level = 12
 )
 
 var (
-	levelsInfo = map[Level]levelInfo{
-		slog.LevelDebug: {
-			"DEBUG", color.MagentaString},
-		slog.LevelInfo: {
-			"INFO ", color.BlueString},
-		slog.LevelWarn: {
-			"WARN ", color.YellowString},
-		slog.LevelError: {
-			"ERROR", color.RedString},
+	envMaxAttrs = sync.OnceValues(func() (int, bool) {
+		n, err := strconv.Atoi(os.Getenv(MaxAttrsEnv))
+		return n, err == nil
+	})
+	levelShortNames = map[string]string{
+		"TRACE": "TRC",
+		"DEBUG": "DBG",
+		"INFO":  "INF",
+		"WARN":  "WRN",
+		"ERROR": "ERR",
+		"FATAL": "FTL",
+	}
+	levelNames = map[Level]string{
+		LevelTrace:      "TRACE",
+		slog.LevelDebug: "DEBUG",
+		slog.LevelInfo:  "INFO",
+		slog.LevelWarn:  "WARN",
+		slog.LevelError: "ERROR",
+		LevelFatal:      "FATAL",
 	}
 )
 
@@ -54,8 +117,8 @@ func SetAsSlogDefault() {
 // NewHandler creates default Handler with default settings for local development
 func NewHandler() Handler {
 	return Handler{
-		Logger:     log.New(os.Stderr, "", 0),
-		TimeLayout: "15:04:05",
+		out:        newOutput(os.Stderr),
+		TimeLayout: TimeLayoutSeconds,
 		SlogOpts: SlogOpts{
 			Level:     slog.LevelDebug,
 			AddSource: false,
@@ -63,16 +126,168 @@ func NewHandler() Handler {
 	}
 }
 
+// WithOutput sets the writer of the records. Whether colors are printed is
+// detected for the writer by DetectColorDepth on the first record, unless set
+// by WithColor or WithColorDepth.
 func (h Handler) WithOutput(output io.Writer) Handler {
-	h.Logger = log.New(output, "", 0)
+	h.out = newOutput(output)
+	return h
+}
+
+// WithErrorWriter sets the writer of the records of slog.LevelWarn and above,
+// or of WithErrorLevel, e.g. os.Stderr while the others go to os.Stdout to keep
+// a piped stdout clean
+func (h Handler) WithErrorWriter(w io.Writer) Handler {
+	h.errOut = newOutput(w)
+	return h
+}
+
+// WithErrorLevel sets the level of the records written by the writer of WithErrorWriter
+func (h Handler) WithErrorLevel(l slog.Leveler) Handler {
+	h.ErrorLevel = l
+	return h
+}
+
+// WithWriter is WithOutput
+func (h Handler) WithWriter(w io.Writer) Handler {
+	return h.WithOutput(w)
+}
+
+// WithGoroutineID sets printing the ID of the goroutine calling the logger
+// after the time, e.g. "g42", to follow the interleaved records of concurrent
+// goroutines. Handle must be called by the goroutine logging the record, as
+// slog.Logger does.
+func (h Handler) WithGoroutineID(v bool) Handler {
+	h.GoroutineID = v
+	return h
+}
+
+// WithContextAttrs sets printing the slogctx fields of the record context
+// dimmed after the message, apart from the attributes of the call, instead of
+// wrapping the handler into slogctx.Handler
+func (h Handler) WithContextAttrs(v bool) Handler {
+	h.ContextAttrs = v
+	return h
+}
+
+// WithMessageGutter sets the prefix of the message lines following the first
+// one, indented to the message column, e.g. "│ "
+func (h Handler) WithMessageGutter(gutter string) Handler {
+	h.MessageGutter = gutter
 	return h
 }
 
+// WithTimeLayout sets the layout of the record times, e.g. TimeLayoutMillis, empty to print no time
 func (h Handler) WithTimeLayout(layout string) Handler {
 	h.TimeLayout = layout
 	return h
 }
 
+// WithClock sets the time source of records without time and of the relative
+// time modes, e.g. a function returning fixed times for golden tests. Without
+// a clock, records without time are printed without time, as slog.Handler
+// requires, instead of with the current time as before
+func (h Handler) WithClock(now func() time.Time) Handler {
+	h.Clock = nil
+	if now != nil {
+		h.Clock = slogx.ClockFunc(now)
+	}
+	return h
+}
+
+// WithJSONIndent sets the indent of the attributes JSON printed on the lines
+// following the message, e.g. "  "; empty prints it on the message line
+func (h Handler) WithJSONIndent(indent string) Handler {
+	h.JSONIndent = indent
+	return h
+}
+
+// WithVertical sets printing each attribute on its own indented line below the
+// message, the attributes of groups indented below the group name
+func (h Handler) WithVertical(v bool) Handler {
+	h.Vertical = v
+	return h
+}
+
+// WithSortedKeys sets printing the attributes sorted by key, at every group level,
+// instead of in the order they were added
+func (h Handler) WithSortedKeys(v bool) Handler {
+	h.SortedKeys = v
+	return h
+}
+
+// WithTheme sets the colors of the record parts, e.g. &ThemeLight
+func (h Handler) WithTheme(t *Theme) Handler {
+	h.Theme = t
+	return h
+}
+
+// WithColor enables or disables colors regardless of the environment
+// and whether the output is a terminal
+func (h Handler) WithColor(v bool) Handler {
+	h.Color = &v
+	return h
+}
+
+// WithColorDepth sets the colors the terminal supports instead of detecting them
+func (h Handler) WithColorDepth(d ColorDepth) Handler {
+	h.ColorDepth = d
+	return h
+}
+
+// WithLevelNames sets the names of the levels, in addition to and instead of
+// the default ones, e.g. {slog.LevelInfo + 2: "NOTICE"}
+func (h Handler) WithLevelNames(names map[Level]string) Handler {
+	h.LevelNames = names
+	return h
+}
+
+// WithIcons sets prefixing the records with the level icons of the theme, e.g. 🟡 for warnings
+func (h Handler) WithIcons(v bool) Handler {
+	h.Icons = v
+	return h
+}
+
+// WithMaxAttrs sets printing only the first n attributes of records followed
+// by a "…+N more" marker, n <= 0 prints all of them
+func (h Handler) WithMaxAttrs(n int) Handler {
+	h.MaxAttrs = n
+	return h
+}
+
+// WithDimKeys sets the keys of the attributes printed in the faint Dim color of
+// the theme, at any group level, e.g. noisy but sometimes useful "host" and "pid"
+func (h Handler) WithDimKeys(keys ...string) Handler {
+	h.DimKeys = keys
+	return h
+}
+
+// WithHiddenKeys sets the keys of the attributes not printed, at any group level
+func (h Handler) WithHiddenKeys(keys ...string) Handler {
+	h.HiddenKeys = keys
+	return h
+}
+
+// WithFormats sets how the durations, times and byte slices are printed
+func (h Handler) WithFormats(f Formats) Handler {
+	h.Formats = f
+	return h
+}
+
+// WithMaxGroupDepth sets printing the groups nested deeper than n, counting the
+// groups of the handler, collapsed to the number of their attributes, e.g. {…3 attrs}
+func (h Handler) WithMaxGroupDepth(n int) Handler {
+	h.MaxGroupDepth = n
+	return h
+}
+
+// WithLevelStyle sets how the level names are displayed, e.g. LevelStyleChar
+// to save horizontal space
+func (h Handler) WithLevelStyle(s LevelStyle) Handler {
+	h.LevelStyle = s
+	return h
+}
+
 func (h Handler) WithLevel(l Level) Handler {
 	h.SlogOpts.Level = l
 	return h
@@ -88,94 +303,554 @@ func (h Handler) WithAddSource(v bool) Handler {
 	return h
 }
 
+// WithSourceFormat sets how the paths of the source files are displayed, e.g.
+// SourceStyleModule for the paths an IDE makes clickable
+func (h Handler) WithSourceFormat(s SourceStyle) Handler {
+	h.SourceStyle = s
+	return h
+}
+
+// WithSourceFunc sets displaying the function names in the source locations, the default
+func (h Handler) WithSourceFunc(v bool) Handler {
+	h.OmitSourceFunc = !v
+	return h
+}
+
 func (h Handler) WithReplaceAttr(replaceAttr func(groups []string, a Attr) Attr) Handler {
 	h.SlogOpts.ReplaceAttr = replaceAttr
 	return h
 }
 
-func (h Handler) Handle(_ context.Context, r Record) error {
-	var outputParts []interface{}
-	if h.TimeLayout != "" {
-		outputParts = append(outputParts, color.WhiteString(r.Time.Format(h.TimeLayout)))
+// WithTee sets forwarding every record also to the handler, e.g. a slog.JSONHandler
+// of a file, to keep both the pretty output and the machine-readable logs. The
+// handler gets the attributes and groups added to the Handler after WithTee
+// and decides by its own level which records it handles.
+func (h Handler) WithTee(handler slog.Handler) Handler {
+	h.Tee = handler
+	return h
+}
+
+func (h Handler) Handle(ctx context.Context, r Record) error {
+	if h.Tee == nil {
+		return h.handle(ctx, r)
 	}
+	var err, teeErr error
+	if h.enabled(r.Level) {
+		err = h.handle(ctx, r)
+	}
+	if h.Tee.Enabled(ctx, r.Level) {
+		teeErr = h.Tee.Handle(ctx, r.Clone())
+	}
+	return errors.Join(err, teeErr)
+}
 
-	outputParts = append(outputParts, h.recordLevel(r), color.CyanString(r.Message))
+// handle prints the record
+func (h Handler) handle(ctx context.Context, r Record) error {
+	out := h.recordOutput(r.Level)
+	theme, depth := h.colors(out)
+	bp := bufPool.Get().(*[]byte)
+	p := renderer{
+		b:        (*bp)[:0],
+		theme:    theme,
+		depth:    depth,
+		dimKeys:  h.DimKeys,
+		maxItems: h.maxAttrs(),
+	}
+	if !h.Vertical {
+		p.indent = h.JSONIndent
+	}
 
-	strAttrs, err := h.recordAttrs(r)
-	if err != nil {
-		return err
+	if h.Icons && len(theme.Icons) != 0 {
+		p.b = append(p.b, theme.icon(r.Level)...)
+	}
+	t := h.recordTime(r)
+	switch {
+	case h.TimeLayout == "" || t.IsZero():
+	case h.SlogOpts.ReplaceAttr == nil:
+		p.space()
+		h.appendTime(&p, out, t)
+	default:
+		if a, ok := h.replaceBuiltin(slog.Time(slog.TimeKey, t)); ok {
+			p.space()
+			if a.Value.Kind() == slog.KindTime {
+				h.appendTime(&p, out, a.Value.Time())
+			} else {
+				p.color(theme.Time, a.Value.String())
+			}
+		}
+	}
+	if h.GoroutineID {
+		p.space()
+		p.colorStart(theme.Dim)
+		n := len(p.b)
+		p.b = append(p.b, 'g')
+		p.b = strconv.AppendUint(p.b, goroutineID(), 10)
+		for len(p.b)-n < goroutineIDWidth {
+			p.b = append(p.b, ' ')
+		}
+		p.colorEnd(theme.Dim)
+	}
+	keyStart := len(p.b)
+	if h.SlogOpts.ReplaceAttr == nil {
+		p.space()
+		h.appendLevel(&p, r.Level, r.Level)
+	} else if a, ok := h.replaceBuiltin(slog.Any(slog.LevelKey, r.Level)); ok {
+		p.space()
+		if l, ok := a.Value.Any().(Level); ok {
+			h.appendLevel(&p, l, r.Level)
+		} else {
+			_, width := h.levelName(r.Level)
+			appendPadded(&p, theme.level(r.Level), a.Value.String(), width)
+		}
 	}
-	if strAttrs != "" {
-		outputParts = append(outputParts, strAttrs)
+	if a, ok := h.replaceBuiltin(slog.String(slog.MessageKey, r.Message)); ok {
+		p.space()
+		h.appendMessage(&p, a.Value.String())
+	}
+	if h.ContextAttrs {
+		if err := h.appendContextAttrs(&p, ctx, r.Level); err != nil {
+			*bp = p.b
+			putBuf(bp)
+			return err
+		}
 	}
 
-	if h.SlogOpts.AddSource {
-		outputParts = append(outputParts, color.GreenString(recordFormatSource(r)))
+	multiline := h.Vertical || h.JSONIndent != ""
+	if !multiline {
+		if err := h.appendAttrs(&p, r, " "); err != nil {
+			*bp = p.b
+			putBuf(bp)
+			return err
+		}
 	}
 
-	h.Logger.Println(outputParts...)
+	if h.SlogOpts.AddSource && r.PC != 0 {
+		if a, ok := h.replaceBuiltin(slog.Any(slog.SourceKey, recordSource(r))); ok {
+			p.space()
+			if src, ok := a.Value.Any().(*slog.Source); ok {
+				p.color(theme.Source, h.formatSource(src))
+			} else {
+				p.color(theme.Source, a.Value.String())
+			}
+		}
+	}
 
-	return nil
+	if multiline {
+		if err := h.appendAttrs(&p, r, "\n"); err != nil {
+			*bp = p.b
+			putBuf(bp)
+			return err
+		}
+	}
+	*bp = append(p.b, '\n')
+	if h.ProcessHeader && out.headerDone.CompareAndSwap(false, true) {
+		if err := h.writeProcessHeader(out); err != nil {
+			putBuf(bp)
+			return err
+		}
+	}
+	if h.CollapseRepeats > 0 {
+		return out.writeRepeated(bp, keyStart, h.CollapseRepeats, theme.Dim, depth)
+	}
+	return out.write(bp)
+}
+
+func (h Handler) Enabled(ctx context.Context, l Level) bool {
+	return h.enabled(l) || h.Tee != nil && h.Tee.Enabled(ctx, l)
 }
 
-func (h Handler) Enabled(_ context.Context, l Level) bool {
+// enabled reports whether the records of the level are printed
+func (h Handler) enabled(l Level) bool {
 	x, y := l.Level(), h.SlogOpts.Level.Level()
 	f := x >= y
 	return f
 }
 
 func (h Handler) WithAttrs(attrs []Attr) SlogHandler {
-	h.Attrs = append(h.Attrs, attrs...)
+	if len(attrs) == 0 {
+		return h
+	}
+	h.Attrs = append(slices.Clip(h.Attrs), attrs...)
+	if h.Tee != nil {
+		h.Tee = h.Tee.WithAttrs(attrs)
+	}
 	return h
 }
 
 func (h Handler) WithGroup(name string) SlogHandler {
-	h.Groups = append(h.Groups, name)
+	if name == "" {
+		return h
+	}
+	if len(h.groupStarts) == len(h.Groups) {
+		h.groupStarts = append(slices.Clip(h.groupStarts), len(h.Attrs))
+	}
+	h.Groups = append(slices.Clip(h.Groups), name)
+	if h.Tee != nil {
+		h.Tee = h.Tee.WithGroup(name)
+	}
 	return h
 }
 
-func (h Handler) recordAttrs(r Record) (string, error) {
-	xs := attrsValues(append(recordAttrs(r), h.Attrs...)...)
+// output is the writer of the records shared by the copies of Handler,
+// writing each record with one Write call
+type output struct {
+	mu    *sync.Mutex // of the writes to w, shared with the outputs of WithAsync
+	w     io.Writer
+	depth func() ColorDepth // of w, detected once
+	async *asyncWriter      // of WithAsync
+
+	timeMu   sync.Mutex
+	prevTime []byte // time column of the previous record, see RepeatedTimeStyle
+
+	repeatsMu sync.Mutex
+	repeats   repeats // of WithCollapseRepeats
+
+	headerDone atomic.Bool // the line of WithProcessHeader is written
+}
+
+var stderr = newOutput(os.Stderr)
+
+func newOutput(w io.Writer) *output {
+	o := &output{mu: new(sync.Mutex), w: consoleOutput(w)}
+	o.depth = sync.OnceValue(func() ColorDepth { return DetectColorDepth(o.w) })
+	return o
+}
+
+func (h Handler) output() *output {
+	if h.out == nil {
+		return stderr
+	}
+	return h.out
+}
+
+// recordOutput returns the output of the records of the level
+func (h Handler) recordOutput(l Level) *output {
+	if h.errOut == nil {
+		return h.output()
+	}
+	errLevel := slog.LevelWarn
+	if h.ErrorLevel != nil {
+		errLevel = h.ErrorLevel.Level()
+	}
+	if l >= errLevel {
+		return h.errOut
+	}
+	return h.output()
+}
+
+// outputs returns the outputs of the records
+func (h Handler) outputs() []*output {
+	if h.errOut == nil {
+		return []*output{h.output()}
+	}
+	return []*output{h.output(), h.errOut}
+}
+
+// write writes the line of the buffer taken from bufPool and returns the buffer
+// to the pool, queueing it in async mode
+func (o *output) write(bp *[]byte) error {
+	if o.async != nil && o.async.enqueue(bp) {
+		return nil
+	}
+	err := o.writeSync(*bp)
+	putBuf(bp)
+	return err
+}
+
+func (o *output) writeSync(b []byte) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	_, err := o.w.Write(b)
+	return err
+}
+
+// bufPool recycles the buffers of the records lines
+var bufPool = sync.Pool{New: func() any {
+	b := make([]byte, 0, 1024)
+	return &b
+}}
+
+func putBuf(bp *[]byte) {
+	if cap(*bp) <= 64<<10 {
+		*bp = (*bp)[:0]
+		bufPool.Put(bp)
+	}
+}
+
+// colors returns the theme and the color depth of the handler for the output
+func (h Handler) colors(out *output) (*Theme, ColorDepth) {
+	theme, depth := h.Theme, h.ColorDepth
+	if theme == nil {
+		theme = &ThemeDark
+	}
+	switch {
+	case h.Color != nil && !*h.Color:
+		depth = ColorDepthNone
+	case depth != ColorDepthAuto:
+	case h.Color != nil:
+		depth = envColorDepth()
+	default:
+		depth = out.depth()
+	}
+	return theme, depth
+}
+
+// appendAttrs appends the separator and the attributes of the handler and the
+// record, nothing if there are no attributes
+func (h Handler) appendAttrs(p *renderer, r Record, sep string) error {
+	if h.appendAttrsFast(p, r, sep) {
+		return nil
+	}
+	p.formats = h.Formats.withDefaults(h.TimeLayout)
+	if h.Vertical {
+		return h.appendVerticalAttrs(p, r, sep)
+	}
+	if h.Plain != nil && h.JSONIndent == "" {
+		return h.appendPlainAttrs(p, r, sep)
+	}
+	attrs, more := h.printedAttrs(r)
+	xs := newObject(attrs, h.SortedKeys)
 	if len(xs) == 0 {
-		return "", nil
+		return nil
+	}
+	p.b = append(p.b, sep...)
+	p.b = append(p.b, h.JSONIndent...)
+	if err := p.object(xs, 0); err != nil {
+		return err
+	}
+	p.b = append(p.b, more...)
+	return nil
+}
+
+// maxAttrs returns the number of the attributes printed, 0 for all
+func (h Handler) maxAttrs() int {
+	if n, ok := envMaxAttrs(); ok {
+		return max(n, 0)
+	}
+	return max(h.MaxAttrs, 0)
+}
+
+// truncate returns the first n elements of xs, all if n is 0, and the marker
+// of the dropped ones
+func truncate[S ~[]E, E any](xs S, n int) (S, string) {
+	if n == 0 || len(xs) <= n {
+		return xs, ""
+	}
+	return xs[:n], fmt.Sprintf(" …+%d more", len(xs)-n)
+}
+
+// appendVerticalAttrs appends the separator and the lines of the attributes
+// nested in the groups of the handler, nothing if there are no attributes
+func (h Handler) appendVerticalAttrs(p *renderer, r Record, sep string) error {
+	attrs, more := h.printedAttrs(r)
+	if len(attrs) == 0 {
+		return nil
+	}
+	p.b = append(p.b, sep...)
+	h.appendVertical(p, "  ", attrs)
+	if more != "" {
+		p.b = append(p.b, ' ')
+		p.b = append(p.b, more...)
+	}
+	p.b = bytes.TrimSuffix(p.b, []byte{'\n'})
+	return nil
+}
+
+func (h Handler) appendVertical(p *renderer, indent string, attrs []Attr) {
+	for _, a := range attrs {
+		theme := p.theme
+		if slices.Contains(h.DimKeys, a.Key) {
+			p.theme = theme.dimmed()
+		}
+		p.b = append(p.b, indent...)
+		p.color(p.theme.Attrs, a.Key+":")
+		if a.Value.Kind() == slog.KindGroup {
+			p.b = append(p.b, '\n')
+			h.appendVertical(p, indent+"  ", a.Value.Group())
+			p.theme = theme
+			continue
+		}
+		p.b = append(p.b, ' ')
+		v := a.Value.Any()
+		n := len(p.b)
+		if !isCollection(v) || p.value(v, 0) != nil {
+			p.b = p.b[:n]
+			s, ok := p.formats.format(v)
+			if !ok {
+				s = a.Value.String()
+			}
+			p.color(p.theme.valueColor(v), s)
+		}
+		p.b = append(p.b, '\n')
+		p.theme = theme
+	}
+}
+
+// recordTime returns the time of the record, of the Clock if the record has
+// none, zero if the handler has no Clock either
+func (h Handler) recordTime(r Record) time.Time {
+	if !r.Time.IsZero() || h.Clock == nil {
+		return r.Time
+	}
+	return h.Clock.Now()
+}
+
+// appendLevel appends the level column of the level, as replaced by
+// ReplaceAttr, colored by the level of the record
+func (h Handler) appendLevel(p *renderer, level, record Level) {
+	name, width := h.levelName(level)
+	appendPadded(p, p.theme.level(record), name, width)
+}
+
+// appendPadded appends s colored and padded by spaces to the display width,
+// the padding out of the escape sequences of the color
+func appendPadded(p *renderer, c Color, s string, width int) {
+	p.color(c, s)
+	for i := displayWidth(s); i < width; i++ {
+		p.b = append(p.b, ' ')
+	}
+}
+
+// appendContextAttrs appends a space and the slogctx fields of the context
+// dimmed, nothing if there are none
+func (h Handler) appendContextAttrs(p *renderer, ctx context.Context, l Level) error {
+	attrs := resolveAttrs(slogctx.LevelAttrs(ctx, l))
+	if h.SlogOpts.ReplaceAttr != nil {
+		attrs = replaceAttrs(h.SlogOpts.ReplaceAttr, nil, attrs)
+	}
+	o := newObject(withoutKeys(attrs, h.HiddenKeys), h.SortedKeys)
+	if len(o) == 0 {
+		return nil
+	}
+	theme, formats := p.theme, p.formats
+	p.theme, p.formats = theme.dimmed(), h.Formats.withDefaults(h.TimeLayout)
+	defer func() { p.theme, p.formats = theme, formats }()
+	p.b = append(p.b, ' ')
+	if h.Plain != nil {
+		n := 0
+		return p.plainObject(h.Plain.withDefaults(), o, "", &n)
+	}
+	indent := p.indent
+	p.indent = ""
+	defer func() { p.indent = indent }()
+	return p.object(o, 0)
+}
+
+// appendMessage appends the message, the lines following the first one indented
+// to the message column and prefixed by the MessageGutter
+func (h Handler) appendMessage(p *renderer, msg string) {
+	first, rest, multiline := strings.Cut(strings.TrimRight(msg, "\r\n"), "\n")
+	p.color(p.theme.Message, first)
+	if !multiline {
+		return
+	}
+	indent := strings.Repeat(" ", visibleWidth(p.b[bytes.LastIndexByte(p.b, '\n')+1:])-displayWidth(first))
+	for _, line := range strings.Split(rest, "\n") {
+		p.b = append(p.b, '\n')
+		p.b = append(p.b, indent...)
+		if h.MessageGutter != "" {
+			p.color(p.theme.Dim, h.MessageGutter)
+		}
+		p.color(p.theme.Message, strings.TrimSuffix(line, "\r"))
 	}
-	for i := len(h.Groups) - 1; i >= 0; i-- {
-		xs = map[string]interface{}{
-			h.Groups[i]: xs,
+}
+
+// replaceBuiltin returns the built-in attribute replaced by ReplaceAttr,
+// false if it is removed
+func (h Handler) replaceBuiltin(a Attr) (Attr, bool) {
+	if h.SlogOpts.ReplaceAttr == nil {
+		return a, true
+	}
+	a = h.SlogOpts.ReplaceAttr(nil, a)
+	a.Value = a.Value.Resolve()
+	return a, a.Key != ""
+}
+
+// levelName returns the displayed name of the level and the width of the
+// level column, the display width of the longest name. A level without a name is
+// displayed as the name of the closest lower level with the offset, e.g. "INFO+2".
+func (h Handler) levelName(l Level) (string, int) {
+	names := levelNames
+	if len(h.LevelNames) != 0 {
+		names = maps.Clone(levelNames)
+		maps.Copy(names, h.LevelNames)
+	}
+	var (
+		name          string
+		at, lowest    Level
+		found, anyone bool
+		width         int
+	)
+	for x, s := range names {
+		width = max(width, displayWidth(h.LevelStyle.apply(s)))
+		if x <= l && (!found || x > at) {
+			name, at, found = s, x, true
+		}
+		if !anyone || x < lowest {
+			lowest, anyone = x, true
+		}
+	}
+	if !found {
+		if !anyone {
+			return l.String(), width
 		}
+		name, at = names[lowest], lowest
 	}
-	s, err := json.Marshal(xs)
-	if err != nil {
-		return "", err
+	name = h.LevelStyle.apply(name)
+	if l != at {
+		name += fmt.Sprintf("%+d", l-at)
 	}
-	return color.WhiteString(string(s)), nil
+	return name, width
 }
 
-func (h Handler) recordLevel(r Record) string {
-	l := levelsInfo[r.Level.Level()]
-	level := l.text
-	if level == "" {
-		level = r.Level.String()
+// apply returns the level name displayed in the style
+func (s LevelStyle) apply(name string) string {
+	switch s {
+	case LevelStyleShort3:
+		if short, ok := levelShortNames[name]; ok {
+			return short
+		}
+		return firstRunes(name, 3)
+	case LevelStyleChar:
+		return firstRunes(name, 1)
 	}
-	if l.colorFunc != nil {
-		level = l.colorFunc(level)
+	return name
+}
+
+// firstRunes returns the first n runes of s
+func firstRunes(s string, n int) string {
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
 	}
-	return level
+	return s
 }
 
-// formats a Source for the log event.
-func recordFormatSource(r Record) string {
-	fs := runtime.CallersFrames([]uintptr{r.PC})
-	f, _ := fs.Next()
+// goroutineIDWidth is the width the goroutine IDs are padded to, e.g. "g42  "
+const goroutineIDWidth = 5
 
-	function := filepath.Base(f.Function)
-	for i, ch := range function {
-		if string(ch) == "." {
-			function = function[i:]
+// goroutineID returns the ID of the calling goroutine parsed from its stack
+// trace header, e.g. "goroutine 42 [running]:", 0 if it is not parsed
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	var id uint64
+	for _, c := range b {
+		if c < '0' || c > '9' {
 			break
 		}
+		id = id*10 + uint64(c-'0')
 	}
-	return fmt.Sprintf("%s:%d%s", filepath.Base(f.File), f.Line, function)
+	return id
+}
+
+func recordSource(r Record) *slog.Source {
+	fs := runtime.CallersFrames([]uintptr{r.PC})
+	f, _ := fs.Next()
+	return &slog.Source{Function: f.Function, File: f.File, Line: f.Line}
 }
 
 func recordAttrs(r Record) []Attr {
@@ -187,14 +862,211 @@ func recordAttrs(r Record) []Attr {
 	return xs
 }
 
-func attrsValues(attrs ...Attr) map[string]interface{} {
-	fields := make(map[string]interface{}, len(attrs))
+// printedAttrs returns the attributes of the handler and the record nested in
+// the groups of the handler, the groups without attributes omitted, and the
+// marker of the attributes dropped by MaxAttrs from the innermost group printed
+func (h Handler) printedAttrs(r Record) ([]Attr, string) {
+	var (
+		attrs []Attr // of the group d+1
+		more  string
+	)
+	for d := len(h.Groups); d >= 0; d-- {
+		xs := h.groupAttrs(d)
+		if d == len(h.Groups) {
+			xs = append(slices.Clip(xs), recordAttrs(r)...)
+		}
+		xs = h.printedGroupAttrs(xs, d)
+		switch {
+		case len(attrs) != 0:
+			xs = append(xs, slog.Attr{Key: h.Groups[d], Value: slog.GroupValue(attrs...)})
+		case len(xs) != 0:
+			xs, more = truncate(xs, h.maxAttrs())
+		}
+		attrs = xs
+	}
+	return attrs, more
+}
+
+// groupAttrs returns the attributes of the handler added in the group d of
+// the handler, 0 for the ones added before the first group
+func (h Handler) groupAttrs(d int) []Attr {
+	if len(h.groupStarts) != len(h.Groups) {
+		if d < len(h.Groups) {
+			return nil
+		}
+		return h.Attrs
+	}
+	start, end := 0, len(h.Attrs)
+	if d > 0 {
+		start = h.groupStarts[d-1]
+	}
+	if d < len(h.Groups) {
+		end = h.groupStarts[d]
+	}
+	return h.Attrs[start:end]
+}
+
+// printedGroupAttrs returns the attributes of the group d of the handler
+// resolved and replaced by ReplaceAttr, without the hidden keys and with the
+// deep groups collapsed
+func (h Handler) printedGroupAttrs(attrs []Attr, d int) []Attr {
+	attrs = resolveAttrs(attrs)
+	if h.SlogOpts.ReplaceAttr != nil {
+		attrs = replaceAttrs(h.SlogOpts.ReplaceAttr, slices.Clip(h.Groups[:d]), attrs)
+	}
+	return h.collapseGroups(withoutKeys(attrs, h.HiddenKeys), d)
+}
+
+// replaceAttrs returns the attributes replaced by the ReplaceAttr function,
+// the ones with empty keys removed, as slog.HandlerOptions.ReplaceAttr does
+func replaceAttrs(replace func([]string, Attr) Attr, groups []string, attrs []Attr) []Attr {
+	xs := make([]Attr, 0, len(attrs))
 	for _, a := range attrs {
 		if a.Value.Kind() == slog.KindGroup {
-			fields[a.Key] = attrsValues(a.Value.Group()...)
+			a.Value = slog.GroupValue(replaceAttrs(replace, append(groups, a.Key), a.Value.Group())...)
 		} else {
-			fields[a.Key] = a.Value.Any()
+			a = replace(groups, a)
+			a.Value = a.Value.Resolve()
+		}
+		if a.Key != "" {
+			xs = append(xs, a)
 		}
 	}
-	return fields
+	return xs
+}
+
+// resolveAttrs returns the attributes with the values of slog.LogValuer
+// resolved, the empty attributes and groups removed and the groups of empty
+// keys inlined, at any group level
+func resolveAttrs(attrs []Attr) []Attr {
+	xs := make([]Attr, 0, len(attrs))
+	for _, a := range attrs {
+		a.Value = a.Value.Resolve()
+		if a.Value.Kind() != slog.KindGroup {
+			if !a.Equal(Attr{}) {
+				xs = append(xs, a)
+			}
+			continue
+		}
+		group := resolveAttrs(a.Value.Group())
+		switch {
+		case len(group) == 0:
+		case a.Key == "":
+			xs = append(xs, group...)
+		default:
+			a.Value = slog.GroupValue(group...)
+			xs = append(xs, a)
+		}
+	}
+	return xs
+}
+
+// withoutKeys returns the attributes without the ones of the keys, at any group
+// level, and without the groups left empty
+func withoutKeys(attrs []Attr, keys []string) []Attr {
+	if len(keys) == 0 {
+		return attrs
+	}
+	xs := make([]Attr, 0, len(attrs))
+	for _, a := range attrs {
+		if slices.Contains(keys, a.Key) {
+			continue
+		}
+		if a.Value.Kind() == slog.KindGroup {
+			group := withoutKeys(a.Value.Group(), keys)
+			if len(group) == 0 {
+				continue
+			}
+			a.Value = slog.GroupValue(group...)
+		}
+		xs = append(xs, a)
+	}
+	return xs
+}
+
+// collapsedGroup is the value of a group nested deeper than Handler.MaxGroupDepth,
+// the number of its attributes
+type collapsedGroup int
+
+func (n collapsedGroup) String() string {
+	return fmt.Sprintf("{…%d attrs}", int(n))
+}
+
+// collapseGroups returns the attributes of the group d of the handler with
+// the groups nested deeper than MaxGroupDepth, counting the groups of the
+// handler, collapsed
+func (h Handler) collapseGroups(attrs []Attr, d int) []Attr {
+	if h.MaxGroupDepth <= 0 {
+		return attrs
+	}
+	return collapseGroups(attrs, max(h.MaxGroupDepth-d, 0))
+}
+
+func collapseGroups(attrs []Attr, depth int) []Attr {
+	xs := make([]Attr, len(attrs))
+	for i, a := range attrs {
+		if a.Value.Kind() == slog.KindGroup {
+			if depth == 0 {
+				a.Value = slog.AnyValue(collapsedGroup(len(a.Value.Group())))
+			} else {
+				a.Value = slog.GroupValue(collapseGroups(a.Value.Group(), depth-1)...)
+			}
+		}
+		xs[i] = a
+	}
+	return xs
+}
+
+// object is the attributes as an ordered JSON object
+type object []member
+
+type member struct {
+	key   string
+	value interface{} // object for groups
+}
+
+// newObject returns the attributes as an object, a repeated key keeping the
+// place of its first attribute and the value of the last one, groups of the
+// same key merged
+func newObject(attrs []Attr, sorted bool) object {
+	var o object
+	for _, a := range attrs {
+		var v interface{}
+		if a.Value.Kind() == slog.KindGroup {
+			v = newObject(a.Value.Group(), sorted)
+		} else {
+			v = a.Value.Any()
+		}
+		i := slices.IndexFunc(o, func(m member) bool { return m.key == a.Key })
+		switch {
+		case i < 0:
+			o = append(o, member{a.Key, v})
+		case isObject(o[i].value) && isObject(v):
+			o[i].value = newObject(append(o[i].value.(object).attrs(), v.(object).attrs()...), sorted)
+		default:
+			o[i].value = v
+		}
+	}
+	if sorted {
+		slices.SortStableFunc(o, func(a, b member) int { return strings.Compare(a.key, b.key) })
+	}
+	return o
+}
+
+func isObject(v interface{}) bool {
+	_, ok := v.(object)
+	return ok
+}
+
+// attrs returns the object as attributes
+func (o object) attrs() []Attr {
+	xs := make([]Attr, len(o))
+	for i, m := range o {
+		if x, ok := m.value.(object); ok {
+			xs[i] = slog.Attr{Key: m.key, Value: slog.GroupValue(x.attrs()...)}
+		} else {
+			xs[i] = slog.Any(m.key, m.value)
+		}
+	}
+	return xs
 }
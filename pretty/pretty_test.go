@@ -0,0 +1,1099 @@
+package pretty
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/slogtest"
+	"time"
+
+	"github.com/fpawel/slogx"
+	"github.com/fpawel/slogx/slogctx"
+)
+
+func TestFastPath(t *testing.T) {
+	newLogger := func(w io.Writer, general bool) *slog.Logger {
+		h := NewHandler().WithOutput(w).WithColorDepth(ColorDepth16)
+		if general {
+			// any ReplaceAttr disables the fast path
+			h = h.WithReplaceAttr(func(_ []string, a slog.Attr) slog.Attr { return a })
+		}
+		return slog.New(h).With("app", "test").WithGroup("req")
+	}
+	var fast, general bytes.Buffer
+	for _, l := range []*slog.Logger{newLogger(&fast, false), newLogger(&general, true)} {
+		l.Info("simple", "s", "a\"b<>&\n\té ", "i", -3, "u", uint64(7), "f", 1e-7, "g", 1e21, "b", true)
+		l.Info("no attrs")
+		l.Info("duplicates", "a", 1, "a", 2)
+		l.Info("complex", "d", time.Second, slog.Group("g", "a", 1))
+		l.With("id", 5).WithGroup("inner").Info("empty group")
+		l.With("id", 5).WithGroup("inner").Info("nested group", "a", 1)
+	}
+	if fast.String() != general.String() {
+		t.Errorf("fast path output differs:\n%s\ngeneral:\n%s", fast.String(), general.String())
+	}
+}
+
+func TestSlogtest(t *testing.T) {
+	for _, general := range []bool{false, true} {
+		var buf bytes.Buffer
+		h := NewHandler().WithOutput(&buf).WithTimeLayout(time.RFC3339Nano).WithColor(false)
+		if general {
+			h = h.WithReplaceAttr(func(_ []string, a slog.Attr) slog.Attr { return a })
+		}
+		err := slogtest.TestHandler(h, func() []map[string]any {
+			var ms []map[string]any
+			for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+				m, err := parseLine(line)
+				if err != nil {
+					t.Fatal(err)
+				}
+				ms = append(ms, m)
+			}
+			return ms
+		})
+		if err != nil {
+			t.Errorf("general %v: %v", general, err)
+		}
+	}
+}
+
+// parseLine parses the line of a record without spaces in the message
+func parseLine(line string) (map[string]any, error) {
+	m := make(map[string]any)
+	head, attrs, ok := strings.Cut(line, " {")
+	if ok {
+		if err := json.Unmarshal([]byte("{"+attrs), &m); err != nil {
+			return nil, err
+		}
+	}
+	fields := strings.Fields(head)
+	if len(fields) == 3 {
+		m[slog.TimeKey], fields = fields[0], fields[1:]
+	}
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("no level and message: %q", line)
+	}
+	m[slog.LevelKey], m[slog.MessageKey] = fields[0], fields[1]
+	return m, nil
+}
+
+func TestFastPathAllocs(t *testing.T) {
+	l := slog.New(NewHandler().WithOutput(io.Discard).WithColorDepth(ColorDepth16))
+	ctx := context.Background()
+	l.InfoContext(ctx, "warm up")
+	allocs := testing.AllocsPerRun(100, func() {
+		l.LogAttrs(ctx, slog.LevelInfo, "request", slog.String("method", "GET"), slog.Int("status", 200), slog.Bool("cached", false))
+	})
+	if allocs != 0 {
+		t.Errorf("got %v allocs per record, want 0", allocs)
+	}
+}
+
+func BenchmarkHandle(b *testing.B) {
+	attrs := []slog.Attr{slog.String("method", "GET"), slog.String("path", "/users/42"), slog.Int("status", 200), slog.Float64("ms", 12.5)}
+	for _, bm := range []struct {
+		name string
+		h    Handler
+	}{
+		{"fast", NewHandler().WithOutput(io.Discard)},
+		{"general", NewHandler().WithOutput(io.Discard).WithReplaceAttr(func(_ []string, a slog.Attr) slog.Attr { return a })},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			l := slog.New(bm.h)
+			ctx := context.Background()
+			b.ReportAllocs()
+			for range b.N {
+				l.LogAttrs(ctx, slog.LevelInfo, "request", attrs...)
+			}
+		})
+	}
+}
+
+func TestAsync(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHandler().WithOutput(&buf).WithTimeLayout("").WithColor(false).WithAsync(4)
+	l := slog.New(h)
+	var want bytes.Buffer
+	for i := range 100 {
+		l.Info("record", "i", i)
+		fmt.Fprintf(&want, "INFO  record {\"i\":%d}\n", i)
+	}
+	if err := h.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != want.String() {
+		t.Fatalf("got after Flush:\n%s", buf.String())
+	}
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	l.Info("after close")
+	if !strings.HasSuffix(buf.String(), "INFO  after close\n") {
+		t.Errorf("record after Close is not written synchronously: %q", buf.String())
+	}
+}
+
+func TestAsyncFlushAll(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHandler().WithOutput(&buf).WithTimeLayout("").WithColor(false).WithAsync(4)
+	defer h.Close()
+	slog.New(h).Info("record")
+	if err := slogx.FlushAll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "INFO  record\n" {
+		t.Fatalf("got after FlushAll:\n%s", buf.String())
+	}
+}
+
+func TestErrorWriter(t *testing.T) {
+	var out, errOut bytes.Buffer
+	l := slog.New(NewHandler().WithOutput(&out).WithErrorWriter(&errOut).WithTimeLayout("").WithColor(false))
+	l.Debug("debug")
+	l.Info("info")
+	l.Warn("warn")
+	l.Error("error")
+	if got, want := out.String(), "DEBUG debug\nINFO  info\n"; got != want {
+		t.Errorf("output: got %q, want %q", got, want)
+	}
+	if got, want := errOut.String(), "WARN  warn\nERROR error\n"; got != want {
+		t.Errorf("error output: got %q, want %q", got, want)
+	}
+}
+
+func TestTee(t *testing.T) {
+	var out, js bytes.Buffer
+	tee := slog.NewJSONHandler(&js, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a Attr) Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return Attr{}
+			}
+			return a
+		},
+	})
+	h := NewHandler().WithOutput(&out).WithTimeLayout("").WithColor(false).WithLevel(slog.LevelInfo).WithTee(tee)
+	l := slog.New(h).With("a", 1).WithGroup("g")
+	l.Debug("debug", "b", 2)
+	l.Info("info", "b", 3)
+	if got, want := out.String(), "INFO  info {\"a\":1,\"g\":{\"b\":3}}\n"; got != want {
+		t.Errorf("output: got %q, want %q", got, want)
+	}
+	want := `{"level":"DEBUG","msg":"debug","a":1,"g":{"b":2}}` + "\n" +
+		`{"level":"INFO","msg":"info","a":1,"g":{"b":3}}` + "\n"
+	if js.String() != want {
+		t.Errorf("tee: got %q, want %q", js.String(), want)
+	}
+}
+
+func TestSourceFormat(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHandler().WithOutput(&buf).WithTimeLayout("").WithColor(false).WithAddSource(true)
+	for _, tt := range []struct {
+		h    Handler
+		want string
+	}{
+		{h, "pretty_test.go:%d.TestSourceFormat"},
+		{h.WithSourceFunc(false), "pretty_test.go:%d"},
+		{h.WithSourceFormat(SourceStylePackage), "pretty/pretty_test.go:%d.TestSourceFormat"},
+		{h.WithSourceFormat(SourceStyleModule).WithSourceFunc(false), "pretty/pretty_test.go:%d"},
+	} {
+		buf.Reset()
+		_, _, line, _ := runtime.Caller(0)
+		slog.New(tt.h).Info("msg")
+		if want := fmt.Sprintf("INFO  msg "+tt.want+"\n", line+1); buf.String() != want {
+			t.Errorf("got %q, want %q", buf.String(), want)
+		}
+	}
+}
+
+func TestTimeModeDelta(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHandler().WithOutput(&buf).WithColor(false).WithTimeMode(TimeModeDelta)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, d := range []time.Duration{0, 12 * time.Millisecond, 1500 * time.Millisecond} {
+		if err := h.WithAttrs(nil).Handle(context.Background(), slog.NewRecord(start.Add(d), slog.LevelInfo, "msg", 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := "     +0s INFO  msg\n   +12ms INFO  msg\n +1.488s INFO  msg\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestUTC(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHandler().WithOutput(&buf).WithColor(false).WithTimeLayout(TimeLayoutMillis).WithUTC(true)
+	tm := time.Date(2024, 1, 1, 10, 20, 30, 456789000, time.FixedZone("UTC+3", 3*60*60))
+	if err := h.Handle(context.Background(), slog.NewRecord(tm, slog.LevelInfo, "msg", 0)); err != nil {
+		t.Fatal(err)
+	}
+	if want := "07:20:30.456 INFO  msg\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestRepeatedTime(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHandler().WithOutput(&buf).WithColor(false).WithRepeatedTime(RepeatedTimeStyleBlank)
+	start := time.Date(2024, 1, 1, 10, 20, 30, 0, time.UTC).Local()
+	for _, d := range []time.Duration{0, 100 * time.Millisecond, time.Second} {
+		if err := h.Handle(context.Background(), slog.NewRecord(start.Add(d), slog.LevelInfo, "msg", 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	second := start.Add(time.Second).Format(TimeLayoutSeconds)
+	want := start.Format(TimeLayoutSeconds) + " INFO  msg\n         INFO  msg\n" + second + " INFO  msg\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestCollapseRepeats(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHandler().WithOutput(&buf).WithColor(false).WithTimeLayout("").WithCollapseRepeats(time.Hour)
+	l := slog.New(h)
+	for range 3 {
+		l.Info("again", "a", 1)
+	}
+	l.Info("other")
+	l.Info("other")
+	if err := h.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	want := "INFO  again {\"a\":1}\n… ×3\nINFO  other\n… ×2\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestCollapseRepeatsTimeout(t *testing.T) {
+	var buf writesRecorder
+	h := NewHandler().WithOutput(&buf).WithColor(false).WithTimeLayout("").WithCollapseRepeats(20 * time.Millisecond)
+	l := slog.New(h)
+	for range 3 {
+		l.Info("again")
+	}
+	time.Sleep(100 * time.Millisecond)
+	if want := "INFO  again\n… ×3\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+	l.Info("closed")
+	l.Info("closed")
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	l.Info("closed")
+	l.Info("closed")
+	time.Sleep(100 * time.Millisecond)
+	if want := "INFO  again\n… ×3\nINFO  closed\n… ×2\nINFO  closed\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestMultilineMessage(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(NewHandler().WithOutput(&buf).WithColor(false).WithTimeLayout("").WithMessageGutter("| "))
+	l.Error("panic: boom\nmain.main()\n", "a", 1)
+	if want := "ERROR panic: boom\n      | main.main() {\"a\":1}\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestPlain(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHandler().WithOutput(&buf).WithColor(false).WithTimeLayout("")
+	for _, f := range []PlainFormat{{}, {KeyValueSep: ": ", PairSep: ", ", Quoting: QuotingAlways}} {
+		slog.New(h.WithPlain(f)).With("app", "my app").WithGroup("req").Info("done", "status", 200, "ok", true)
+	}
+	want := "INFO  done app=\"my app\" req.status=200 req.ok=true\n" +
+		"INFO  done app: \"my app\", req.status: 200, req.ok: true\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestGoroutineID(t *testing.T) {
+	id := goroutineID()
+	other := make(chan uint64)
+	go func() { other <- goroutineID() }()
+	if o := <-other; id == 0 || o == 0 || o == id {
+		t.Errorf("got IDs %d and %d", id, o)
+	}
+}
+
+func TestContextAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(NewHandler().WithOutput(&buf).WithColor(false).WithTimeLayout("").WithContextAttrs(true))
+	ctx := slogctx.WithValues(context.Background(), "request_id", "abc")
+	ctx = slogctx.WithDebugValues(ctx, "verbose", 1)
+	l.InfoContext(ctx, "done", "status", 200)
+	l.DebugContext(ctx, "debug")
+	l.Info("no context")
+	want := "INFO  done {\"request_id\":\"abc\"} {\"status\":200}\n" +
+		"DEBUG debug {\"request_id\":\"abc\",\"verbose\":1}\n" +
+		"INFO  no context\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestClockTimeModes(t *testing.T) {
+	var buf bytes.Buffer
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time {
+		now = now.Add(250 * time.Millisecond)
+		return now
+	}
+	h := NewHandler().WithOutput(&buf).WithColor(false).WithClock(clock)
+	for _, m := range []TimeMode{TimeModeSinceStart, TimeModeDelta} {
+		l := slog.New(h.WithTimeMode(m))
+		l.Info("a")
+		l.Info("b")
+	}
+	want := "      0s INFO  a\n   250ms INFO  b\n     +0s INFO  a\n  +250ms INFO  b\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestClockZeroTime(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHandler().WithOutput(&buf).WithColor(false).WithTimeLayout(time.TimeOnly)
+	r := slog.NewRecord(time.Time{}, slog.LevelInfo, "no clock", 0)
+	if err := h.Handle(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+	r = slog.NewRecord(time.Time{}, slog.LevelInfo, "clock", 0)
+	clock := func() time.Time { return time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC) }
+	if err := h.WithClock(clock).Handle(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+	r = slog.NewRecord(time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC), slog.LevelInfo, "record time", 0)
+	if err := h.WithClock(clock).WithUTC(true).Handle(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+	want := "INFO  no clock\n12:30:00 INFO  clock\n08:00:00 INFO  record time\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestBanner(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHandler().WithOutput(&buf).WithColor(false)
+	if err := h.Banner("setup"); err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Repeat("─", 36) + " setup " + strings.Repeat("─", 37) + "\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+type flushWriter struct {
+	bytes.Buffer
+	flushed int
+}
+
+func (w *flushWriter) Flush() error {
+	w.flushed++
+	return nil
+}
+
+type closeHandler struct {
+	slog.Handler
+	closed bool
+}
+
+func (h *closeHandler) Close() error {
+	h.closed = true
+	return nil
+}
+
+func TestClose(t *testing.T) {
+	var w flushWriter
+	tee := &closeHandler{Handler: slog.NewTextHandler(io.Discard, nil)}
+	h := NewHandler().WithOutput(&w).WithTee(tee).WithAsync(4)
+	slog.New(h).Info("msg")
+	if err := slogx.Close(h); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(w.String(), "msg") || w.flushed == 0 || !tee.closed {
+		t.Errorf("got output %q, %d flushes, tee closed %v", w.String(), w.flushed, tee.closed)
+	}
+}
+
+func TestWriterColorDetection(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("FORCE_COLOR", "")
+	t.Setenv("CLICOLOR_FORCE", "")
+	var buf bytes.Buffer
+	h := NewHandler().WithTimeLayout("")
+	for _, tt := range []struct {
+		h     Handler
+		color bool
+	}{
+		{h.WithOutput(&buf), false},
+		{h.WithOutput(&buf).WithColor(true), true},
+		{h.WithColor(false).WithWriter(&buf), false},
+	} {
+		buf.Reset()
+		slog.New(tt.h).Info("msg")
+		if got := strings.Contains(buf.String(), "\x1b["); got != tt.color {
+			t.Errorf("got colors %v in %q", got, buf.String())
+		}
+	}
+}
+
+func TestProcessHeader(t *testing.T) {
+	var buf bytes.Buffer
+	l := slog.New(NewHandler().WithOutput(&buf).WithColor(false).WithTimeLayout("").WithProcessHeader(true))
+	l.Info("one")
+	l.Info("two")
+	lines := strings.Split(buf.String(), "\n")
+	if len(lines) != 4 || !strings.Contains(lines[0], fmt.Sprintf(" pid=%d", os.Getpid())) || lines[1] != "INFO  one" {
+		t.Errorf("got %q", buf.String())
+	}
+}
+
+func TestLevelAlignment(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHandler().WithOutput(&buf).WithColorDepth(ColorDepth16).WithTimeLayout("").
+		WithLevelNames(map[Level]string{slog.LevelInfo: "ИНФО"})
+	replaced := h.WithReplaceAttr(func(groups []string, a Attr) Attr {
+		if a.Key == slog.LevelKey {
+			return slog.String(a.Key, "OK")
+		}
+		return a
+	})
+	for _, h := range []Handler{h, h.WithLevelStyle(LevelStyleShort3), replaced} {
+		slog.New(h).Info("msg")
+		slog.New(h).Error("msg")
+	}
+	var columns []int
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		columns = append(columns, visibleWidth([]byte(line[:strings.Index(line, "msg")])))
+	}
+	if want := []int{6, 6, 4, 4, 6, 6}; !slices.Equal(columns, want) {
+		t.Errorf("got message columns %v, want %v in:\n%s", columns, want, buf.String())
+	}
+}
+
+func TestTheme(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHandler().WithOutput(&buf).WithTimeLayout("").WithTheme(&ThemeLight)
+	for _, d := range []ColorDepth{ColorDepthTrue, ColorDepth256, ColorDepth16, ColorDepthNone} {
+		slog.New(h.WithColorDepth(d)).Info("msg")
+	}
+	slog.New(h.WithTheme(&ThemeMonochrome).WithColorDepth(ColorDepthTrue)).Info("msg")
+	slog.New(h.WithTheme(nil).WithColorDepth(ColorDepth16)).Info("msg")
+	want := "\x1b[38;2;0;0;215mINFO\x1b[0m  \x1b[38;2;0;95;135mmsg\x1b[0m\n" +
+		"\x1b[38;5;20mINFO\x1b[0m  \x1b[38;5;24mmsg\x1b[0m\n" +
+		"\x1b[94mINFO\x1b[0m  \x1b[34mmsg\x1b[0m\n" +
+		"INFO  msg\n" +
+		"INFO  msg\n" +
+		"\x1b[34mINFO\x1b[0m  \x1b[36mmsg\x1b[0m\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestDetectColorDepth(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("CLICOLOR_FORCE", "")
+	for _, tt := range []struct {
+		force, colorterm, term string
+		want                   ColorDepth
+	}{
+		{"", "truecolor", "xterm-256color", ColorDepthNone},
+		{"1", "", "xterm", ColorDepth16},
+		{"1", "", "xterm-256color", ColorDepth256},
+		{"1", "truecolor", "xterm-256color", ColorDepthTrue},
+		{"1", "24bit", "", ColorDepthTrue},
+		{"2", "", "", ColorDepth256},
+		{"3", "", "", ColorDepthTrue},
+	} {
+		t.Setenv("FORCE_COLOR", tt.force)
+		t.Setenv("COLORTERM", tt.colorterm)
+		t.Setenv("TERM", tt.term)
+		if got := DetectColorDepth(io.Discard); got != tt.want {
+			t.Errorf("FORCE_COLOR=%q COLORTERM=%q TERM=%q: got %d, want %d", tt.force, tt.colorterm, tt.term, got, tt.want)
+		}
+	}
+}
+
+func TestJSONIndent(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHandler().WithOutput(&buf).WithColor(false).WithTimeLayout("").WithJSONIndent("  ")
+	l := slog.New(h).With("app", "api").WithGroup("req")
+	l.Info("done", "status", 200, slog.Group("user", "id", 7, "tags", []string{"a", "b"}),
+		"point", struct{ X, Y int }{1, 2})
+	slog.New(h).Info("no attrs")
+	want := `INFO  done
+  {
+    "app": "api",
+    "req": {
+      "status": 200,
+      "user": {
+        "id": 7,
+        "tags": [
+          "a",
+          "b"
+        ]
+      },
+      "point": {
+        "X": 1,
+        "Y": 2
+      }
+    }
+  }
+INFO  no attrs
+`
+	if buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+	_, attrs, _ := strings.Cut(strings.TrimSuffix(buf.String(), "INFO  no attrs\n"), "\n")
+	if !json.Valid([]byte(attrs)) {
+		t.Errorf("invalid JSON %s", attrs)
+	}
+}
+
+func TestVertical(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHandler().WithOutput(&buf).WithColor(false).WithTimeLayout("").WithVertical(true)
+	l := slog.New(h).With("app", "api").WithGroup("req")
+	l.Info("done", "status", 200, slog.Group("user", "id", 7, "tags", []string{"a", "b"}), "took", time.Second)
+	slog.New(h).Info("no attrs")
+	slog.New(h.WithMaxAttrs(1)).Info("max", "a", "x y", "b", 2)
+	want := `INFO  done
+  app: api
+  req:
+    status: 200
+    user:
+      id: 7
+      tags: ["a","b"]
+    took: 1s
+INFO  no attrs
+INFO  max
+  a: x y
+  …+1 more
+`
+	if buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestSortedKeys(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHandler().WithOutput(&buf).WithColor(false).WithTimeLayout("")
+	for _, sorted := range []bool{false, true} {
+		l := slog.New(h.WithSortedKeys(sorted)).With("z", 1).WithGroup("req")
+		l.Info("msg", "b", 2, slog.Group("g", "y", 1, "x", 2), "a", map[string]int{"n": 1, "m": 2})
+	}
+	want := `INFO  msg {"z":1,"req":{"b":2,"g":{"y":1,"x":2},"a":{"m":2,"n":1}}}
+INFO  msg {"req":{"a":{"m":2,"n":1},"b":2,"g":{"x":2,"y":1}},"z":1}
+`
+	if buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestMaxAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHandler().WithOutput(&buf).WithColor(false).WithTimeLayout("").WithMaxAttrs(2)
+	slog.New(h).Info("msg", "a", 1, "b", 2, "c", 3, "d", 4)
+	slog.New(h).Info("msg", "a", 1, "b", 2)
+	slog.New(h).With("x", 0).WithGroup("g").Info("msg", "a", 1, "b", 2, "c", 3)
+	slog.New(h).Info("msg", "s", []int{1, 2, 3, 4}, "m", map[string]int{"a": 1, "b": 2, "c": 3})
+	slog.New(h.WithPlain(PlainFormat{})).Info("msg", "a", 1, "b", 2, "c", 3)
+	want := `INFO  msg {"a":1,"b":2} …+2 more
+INFO  msg {"a":1,"b":2}
+INFO  msg {"x":0,"g":{"a":1,"b":2}} …+1 more
+INFO  msg {"s":[1,2,…+2 more],"m":{"a":1,"b":2,…+1 more}}
+INFO  msg a=1 b=2 …+1 more
+`
+	if buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	// the environment variable overrides the handler for a run
+	defer func(f func() (int, bool)) { envMaxAttrs = f }(envMaxAttrs)
+	envMaxAttrs = func() (int, bool) { return 0, true }
+	buf.Reset()
+	slog.New(h).Info("msg", "a", 1, "b", 2, "c", 3)
+	if want := "INFO  msg {\"a\":1,\"b\":2,\"c\":3}\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+// colorTags returns s with the escape sequences of the colors as tags, e.g. <92>"x"</>
+func colorTags(s string) string {
+	s = strings.ReplaceAll(s, "\x1b[0m", "</>")
+	return regexp.MustCompile(`\x1b\[([\d;]+)m`).ReplaceAllString(s, "<$1>")
+}
+
+func TestValueColors(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHandler().WithOutput(&buf).WithColorDepth(ColorDepth16).WithTimeLayout("")
+	slog.New(h).Info("m", "s", "x", "n", 1, "b", true, "z", nil, "d", time.Second,
+		"e", errors.New("boom"), "o", struct{ A int }{1})
+	want := `<34>INFO</>  <36>m</> <37>{</>` +
+		`<37>"s":</><92>"x"</><37>,</>` +
+		`<37>"n":</><96>1</><37>,</>` +
+		`<37>"b":</><95>true</><37>,</>` +
+		`<37>"z":</><90>null</><37>,</>` +
+		`<37>"d":</><94>"1s"</><37>,</>` +
+		`<37>"e":</><91>"boom"</><37>,</>` +
+		`<37>"o":</><37>{"A":1}</>` +
+		`<37>}</>` + "\n"
+	if got := colorTags(buf.String()); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestDimAndHiddenKeys(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHandler().WithOutput(&buf).WithColorDepth(ColorDepth16).WithTimeLayout("").
+		WithDimKeys("host").WithHiddenKeys("pid")
+	slog.New(h).With("host", "h1", "pid", 42).WithGroup("g").Info("m", "pid", 1, "host", "h2", "n", 3)
+	slog.New(h.WithVertical(true)).Info("m", "host", "h1", "pid", 1, "n", 3)
+	want := `<34>INFO</>  <36>m</> <37>{</><90>"host":</><90>"h1"</><37>,</>` +
+		`<37>"g":</><37>{</><90>"host":</><90>"h2"</><37>,</><37>"n":</><96>3</><37>}</><37>}</>
+<34>INFO</>  <36>m</>
+  <90>host:</> <90>h1</>
+  <37>n:</> <96>3</>
+`
+	if got := colorTags(buf.String()); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	// the keys are hidden at any group level
+	buf.Reset()
+	slog.New(h.WithColor(false)).Info("m", slog.Group("x", "pid", 1, "host", "h2"), slog.Group("y", "pid", 2))
+	if want := "INFO  m {\"x\":{\"host\":\"h2\"}}\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestFormats(t *testing.T) {
+	var buf bytes.Buffer
+	tm := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	h := NewHandler().WithOutput(&buf).WithColor(false).WithTimeLayout("")
+	slog.New(h).Info("m", "d", 1200*time.Millisecond, "t", tm, "b", []byte{1, 0xab}, "long", []byte(strings.Repeat("x", 33)))
+	slog.New(h.WithFormats(Formats{
+		Duration: func(d time.Duration) string { return fmt.Sprint(d.Milliseconds(), "ms") },
+		Time:     func(t time.Time) string { return t.Format(time.DateOnly) },
+		Bytes:    func(b []byte) string { return string(b) },
+	})).Info("m", "d", time.Second, "t", tm, "b", []byte("hi"))
+	slog.New(h.WithVertical(true)).Info("m", "d", time.Second, "b", []byte{1})
+	slog.New(h.WithPlain(PlainFormat{})).Info("m", "d", time.Second, "b", []byte{1})
+	want := `INFO  m {"d":"1.2s","t":"2024-01-02T03:04:05Z","b":"01ab (2 bytes hex)",` +
+		`"long":"eHh4eHh4eHh4eHh4eHh4eHh4eHh4eHh4eHh4eHh4eHh4 (33 bytes base64)"}
+INFO  m {"d":"1000ms","t":"2024-01-02","b":"hi"}
+INFO  m
+  d: 1s
+  b: 01 (1 bytes hex)
+INFO  m d=1s b="01 (1 bytes hex)"
+`
+	if buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+type textKey int
+
+func (k textKey) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprint("k", int(k))), nil
+}
+
+func TestCollections(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHandler().WithOutput(&buf).WithColorDepth(ColorDepth16).WithTimeLayout("")
+	slog.New(h).Info("m", "m", map[string]any{"b": time.Second, "a": []any{1, "x", nil}})
+	want := `<34>INFO</>  <36>m</> <37>{</><37>"m":</><37>{</>` +
+		`<37>"a":</><37>[</><96>1</><37>,</><92>"x"</><37>,</><90>null</><37>]</><37>,</>` +
+		`<37>"b":</><94>"1s"</><37>}</><37>}</>` + "\n"
+	if got := colorTags(buf.String()); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	buf.Reset()
+	h = h.WithColor(false)
+	slog.New(h).Info("m",
+		"m", map[string]any{"b": time.Second, "a": []any{1, "x", nil, map[int]bool{2: true, 1: false}}},
+		"k", map[textKey]int{2: 2, 1: 1},
+		"nil", []int(nil),
+		"empty", map[string]int{},
+		"arr", [2]string{"p", "q"},
+		"bytes", [][]byte{{1}})
+	slog.New(h.WithJSONIndent("  ")).Info("m", "s", []map[string]int{{"a": 1}})
+	want = `INFO  m {"m":{"a":[1,"x",null,{"1":false,"2":true}],"b":"1s"},"k":{"k1":1,"k2":2},` +
+		`"nil":null,"empty":{},"arr":["p","q"],"bytes":["01 (1 bytes hex)"]}
+INFO  m
+  {
+    "s": [
+      {
+        "a": 1
+      }
+    ]
+  }
+`
+	if buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestMaxGroupDepth(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHandler().WithOutput(&buf).WithColor(false).WithTimeLayout("").WithMaxGroupDepth(2)
+	deep := slog.Group("a", "x", 1, slog.Group("b", "y", 2, slog.Group("c", "z", 3, "w", 4)))
+	slog.New(h).Info("m", deep)
+	slog.New(h).WithGroup("g").Info("m", deep)
+	slog.New(h).WithGroup("g").WithGroup("h").Info("m", deep, "n", 1)
+	slog.New(h.WithVertical(true)).Info("m", deep)
+	slog.New(h.WithMaxGroupDepth(0)).Info("m", deep)
+	want := `INFO  m {"a":{"x":1,"b":{"y":2,"c":{…2 attrs}}}}
+INFO  m {"g":{"a":{"x":1,"b":{…2 attrs}}}}
+INFO  m {"g":{"h":{"a":{…2 attrs},"n":1}}}
+INFO  m
+  a:
+    x: 1
+    b:
+      y: 2
+      c: {…2 attrs}
+INFO  m {"a":{"x":1,"b":{"y":2,"c":{"z":3,"w":4}}}}
+`
+	if buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+type logUser struct {
+	id     int
+	secret string
+}
+
+func (u logUser) LogValue() slog.Value {
+	return slog.GroupValue(slog.Int("id", u.id))
+}
+
+type logSecret string
+
+func (logSecret) LogValue() slog.Value {
+	return slog.StringValue("***")
+}
+
+func TestLogValuer(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHandler().WithOutput(&buf).WithColor(false).WithTimeLayout("")
+	slog.New(h).With("owner", logUser{1, "a"}).Info("m", "user", logUser{7, "b"}, "pw", logSecret("c"),
+		slog.Group("g", "u", logUser{8, "d"}), "list", []any{logUser{9, "e"}, logSecret("f")})
+	slog.New(h.WithVertical(true)).Info("m", "user", logUser{7, "b"}, "pw", logSecret("c"))
+	slog.New(h.WithPlain(PlainFormat{})).Info("m", "user", logUser{7, "b"}, "pw", logSecret("c"))
+	want := `INFO  m {"owner":{"id":1},"user":{"id":7},"pw":"***","g":{"u":{"id":8}},"list":[{"id":9},"***"]}
+INFO  m
+  user:
+    id: 7
+  pw: ***
+INFO  m user.id=7 pw=***
+`
+	if buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestReplaceAttrBuiltin(t *testing.T) {
+	var buf bytes.Buffer
+	var keys []string
+	h := NewHandler().WithOutput(&buf).WithColor(false).WithTimeLayout(time.TimeOnly).WithAddSource(true).
+		WithReplaceAttr(func(groups []string, a slog.Attr) slog.Attr {
+			keys = append(keys, strings.Join(append(groups, a.Key), "."))
+			switch {
+			case a.Key == "secret":
+				return slog.String(a.Key, "***")
+			case len(groups) != 0:
+			case a.Key == slog.TimeKey, a.Key == slog.SourceKey:
+				return slog.Attr{}
+			case a.Key == slog.LevelKey:
+				return slog.String(a.Key, "NOTE")
+			case a.Key == slog.MessageKey:
+				return slog.String(a.Key, strings.ToUpper(a.Value.String()))
+			}
+			return a
+		})
+	slog.New(h).WithGroup("req").Info("done", "secret", "x", "time", 1, slog.Group("g", "secret", "y"))
+	slog.New(h.WithReplaceAttr(func(groups []string, a slog.Attr) slog.Attr {
+		switch a.Key {
+		case slog.TimeKey:
+			return slog.String(a.Key, "T")
+		case slog.LevelKey:
+			return slog.Any(a.Key, slog.LevelWarn)
+		case slog.SourceKey:
+			return slog.String(a.Key, "main.go:1")
+		}
+		return a
+	})).Info("m")
+	want := `NOTE  DONE {"req":{"secret":"***","time":1,"g":{"secret":"***"}}}
+T WARN  m main.go:1
+`
+	if buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+	if want := []string{"time", "level", "msg", "req.secret", "req.time", "req.g.secret", "source"}; !slices.Equal(keys, want) {
+		t.Errorf("got keys %v, want %v", keys, want)
+	}
+}
+
+// writesRecorder records the Write calls
+type writesRecorder struct {
+	mu     sync.Mutex
+	writes []string
+}
+
+func (w *writesRecorder) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writes = append(w.writes, string(b))
+	return len(b), nil
+}
+
+func (w *writesRecorder) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return strings.Join(w.writes, "")
+}
+
+func TestHandleConcurrent(t *testing.T) {
+	for _, general := range []bool{false, true} {
+		var w writesRecorder
+		h := NewHandler().WithOutput(&w).WithColor(false).WithTimeLayout("")
+		if general {
+			h = h.WithReplaceAttr(func(_ []string, a slog.Attr) slog.Attr { return a })
+		}
+		l := slog.New(h).With("app", "test")
+		var wg sync.WaitGroup
+		for g := range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range 100 {
+					l.Info("record", "g", g, "i", i, "s", strings.Repeat("x", i))
+				}
+			}()
+		}
+		wg.Wait()
+
+		if len(w.writes) != 800 {
+			t.Fatalf("general %v: got %d writes, want 800", general, len(w.writes))
+		}
+		seen := make(map[string]bool)
+		for _, s := range w.writes {
+			var g, i int
+			if _, err := fmt.Sscanf(s, "INFO  record {\"app\":\"test\",\"g\":%d,\"i\":%d,", &g, &i); err != nil {
+				t.Fatalf("general %v: %v in write %q", general, err, s)
+			}
+			want := fmt.Sprintf("INFO  record {\"app\":\"test\",\"g\":%d,\"i\":%d,\"s\":%q}\n", g, i, strings.Repeat("x", i))
+			if s != want {
+				t.Fatalf("general %v: got write %q, want %q", general, s, want)
+			}
+			seen[s] = true
+		}
+		if len(seen) != 800 {
+			t.Errorf("general %v: got %d distinct lines, want 800", general, len(seen))
+		}
+	}
+}
+
+func TestIcons(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHandler().WithOutput(&buf).WithColor(false).WithTimeLayout("").WithIcons(true)
+	l := slog.New(h)
+	l.Debug("msg")
+	l.Info("msg")
+	l.Warn("msg")
+	l.Error("msg")
+	slog.New(h.WithIcons(false)).Warn("msg")
+	want := "🔵 DEBUG msg\n🟢 INFO  msg\n🟡 WARN  msg\n🔴 ERROR msg\nWARN  msg\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+
+	// icons of different widths are padded to the widest one
+	buf.Reset()
+	theme := ThemeMonochrome
+	theme.Icons = map[Level]string{slog.LevelInfo: "i", slog.LevelWarn: "🟡", slog.LevelError: "🔴"}
+	l = slog.New(h.WithTheme(&theme).WithColorDepth(ColorDepth16))
+	l.Debug("msg")
+	l.Info("msg")
+	l.Warn("msg")
+	l.Error("msg")
+	var columns []int
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		columns = append(columns, visibleWidth([]byte(line[:strings.Index(line, "msg")])))
+	}
+	if want := []int{9, 9, 9, 9}; !slices.Equal(columns, want) {
+		t.Errorf("got message columns %v, want %v in:\n%s", columns, want, buf.String())
+	}
+	if lines := strings.Split(buf.String(), "\n"); !strings.HasPrefix(lines[0], "i  ") || !strings.HasPrefix(lines[2], "🟡 ") {
+		t.Errorf("got %q", buf.String())
+	}
+}
+
+func TestNewOptions(t *testing.T) {
+	tm := time.Date(2024, 1, 2, 3, 4, 5, 678000000, time.UTC)
+	output := func(h Handler) string {
+		var buf bytes.Buffer
+		h = h.WithOutput(&buf)
+		l := slog.New(h).With("host", "h1", "pid", 42).WithGroup("req")
+		for _, level := range []Level{slog.LevelDebug, slog.LevelInfo, slog.LevelWarn} {
+			if !l.Enabled(context.Background(), level) {
+				continue
+			}
+			r := slog.NewRecord(tm, level, "msg", 0)
+			r.Add("b", 2, "a", []int{1, 2, 3}, slog.Group("g", slog.Group("h", "x", 1)))
+			if err := l.Handler().Handle(context.Background(), r); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return buf.String()
+	}
+
+	if got, want := output(New(Options{})), output(NewHandler()); got != want {
+		t.Errorf("defaults: got\n%s\nwant\n%s", got, want)
+	}
+
+	color := true
+	got := output(New(Options{
+		Level:         slog.LevelInfo,
+		TimeLayout:    TimeLayoutMillis,
+		UTC:           true,
+		SortedKeys:    true,
+		MaxAttrs:      3,
+		MaxGroupDepth: 2,
+		DimKeys:       []string{"host"},
+		HiddenKeys:    []string{"pid"},
+		Theme:         &ThemeLight,
+		ColorDepth:    ColorDepth256,
+		Color:         &color,
+		LevelStyle:    LevelStyleShort3,
+		Icons:         true,
+	}))
+	want := output(NewHandler().
+		WithLevel(slog.LevelInfo).
+		WithTimeLayout(TimeLayoutMillis).
+		WithUTC(true).
+		WithSortedKeys(true).
+		WithMaxAttrs(3).
+		WithMaxGroupDepth(2).
+		WithDimKeys("host").
+		WithHiddenKeys("pid").
+		WithTheme(&ThemeLight).
+		WithColorDepth(ColorDepth256).
+		WithColor(true).
+		WithLevelStyle(LevelStyleShort3).
+		WithIcons(true))
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if !strings.HasPrefix(want, "🟢 \x1b[38;5;59m03:04:05.678\x1b[0m \x1b[38;5;20mINF\x1b[0m") || strings.Contains(want, "DBG") {
+		t.Errorf("options not applied:\n%q", want)
+	}
+
+	var buf bytes.Buffer
+	slog.New(New(Options{Output: &buf, OmitTime: true, Color: new(bool), Vertical: true})).Info("msg", "a", 1)
+	if want := "INFO  msg\n  a: 1\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestPresets(t *testing.T) {
+	tm := time.Date(2024, 1, 2, 3, 4, 5, 678901000, time.UTC)
+	var pcs [1]uintptr
+	runtime.Callers(1, pcs[:])
+	_, _, line, _ := runtime.Caller(0)
+	handle := func(h Handler, attrs ...any) string {
+		var buf bytes.Buffer
+		r := slog.NewRecord(tm, slog.LevelWarn, "failed\nmain.main()", pcs[0])
+		r.Add(attrs...)
+		if err := h.WithOutput(&buf).WithColor(false).WithUTC(true).Handle(context.Background(), r); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	got := handle(PresetCompact(), "a", 1, "b", 2, "c", 3, "d", 4, "e", 5, "f", 6, "g", 7, "h", 8, "i", 9, "j", 10)
+	got += handle(PresetCompact().WithAddSource(true), "s", []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10})
+	want := fmt.Sprintf(`03:04:05 WRN failed
+             main.main() {"a":1,"b":2,"c":3,"d":4,"e":5,"f":6,"g":7,"h":8} …+2 more
+03:04:05 WRN failed
+             main.main() {"s":[1,2,3,4,5,6,7,8,…+2 more]} pretty_test.go:%d
+`, line-1)
+	if got != want {
+		t.Errorf("compact: got\n%s\nwant\n%s", got, want)
+	}
+
+	got = handle(PresetExpanded(), "a", 1, slog.Group("g", "b", "x y"))
+	want = fmt.Sprintf(`2024-01-02 03:04:05.678901 WARN  failed
+                                 │ main.main() pretty/pretty_test.go:%d.TestPresets
+  a: 1
+  g:
+    b: x y
+`, line-1)
+	if got != want {
+		t.Errorf("expanded: got\n%s\nwant\n%s", got, want)
+	}
+}
+
+// overlapWriter counts the Write calls overlapping another one
+type overlapWriter struct {
+	writing, overlaps atomic.Int32
+}
+
+func (w *overlapWriter) Write(p []byte) (int, error) {
+	if !w.writing.CompareAndSwap(0, 1) {
+		w.overlaps.Add(1)
+		return len(p), nil
+	}
+	time.Sleep(10 * time.Microsecond)
+	w.writing.Store(0)
+	return len(p), nil
+}
+
+func TestAsyncSharedWriter(t *testing.T) {
+	var w overlapWriter
+	h := NewHandler().WithOutput(&w).WithColor(false)
+	async := h.WithAsync(4)
+	defer async.Close()
+	var wg sync.WaitGroup
+	for _, l := range []*slog.Logger{slog.New(h), slog.New(async)} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 200 {
+				l.Info("record")
+			}
+		}()
+	}
+	wg.Wait()
+	if err := async.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if n := w.overlaps.Load(); n != 0 {
+		t.Errorf("%d writes of the sync and async handlers overlapped", n)
+	}
+}
@@ -0,0 +1,327 @@
+package pretty
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// renderer prints the attributes as JSON, the tokens colored by the theme
+type renderer struct {
+	b        []byte
+	theme    *Theme
+	depth    ColorDepth
+	indent   string   // of the lines of the members if not empty, as by json.MarshalIndent
+	dimKeys  []string // keys of the members printed dimmed
+	formats  Formats
+	maxItems int // of the maps and slices printed, 0 for all
+}
+
+// Formats is how the values of the types are printed; nil functions print
+// them by default: durations as by time.Duration.String, times in the time
+// layout of the handler and byte slices in hex or base64 with the size
+type Formats struct {
+	Duration func(time.Duration) string
+	Time     func(time.Time) string
+	Bytes    func([]byte) string
+}
+
+// withDefaults returns the formats with the nil functions set to the default ones
+func (f Formats) withDefaults(timeLayout string) Formats {
+	if f.Duration == nil {
+		f.Duration = time.Duration.String
+	}
+	if f.Time == nil {
+		if timeLayout == "" {
+			timeLayout = time.RFC3339Nano
+		}
+		f.Time = func(t time.Time) string { return t.Format(timeLayout) }
+	}
+	if f.Bytes == nil {
+		f.Bytes = formatBytes
+	}
+	return f
+}
+
+// format returns v formatted if it is of one of the types of the formats
+func (f Formats) format(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case time.Duration:
+		return f.Duration(v), true
+	case time.Time:
+		return f.Time(v), true
+	case []byte:
+		return f.Bytes(v), true
+	}
+	return "", false
+}
+
+// formatBytes returns the short slices in hex and the long ones in base64, with the size
+func formatBytes(b []byte) string {
+	if len(b) <= 32 {
+		return hex.EncodeToString(b) + " (" + strconv.Itoa(len(b)) + " bytes hex)"
+	}
+	return base64.StdEncoding.EncodeToString(b) + " (" + strconv.Itoa(len(b)) + " bytes base64)"
+}
+
+// color appends s colored
+func (p *renderer) color(c Color, s string) {
+	p.b = c.appendTo(p.b, p.depth, s)
+}
+
+// colorStart starts appending the text colored
+func (p *renderer) colorStart(c Color) {
+	if c.set && p.depth > ColorDepthNone {
+		p.b = c.appendStart(p.b, p.depth)
+	}
+}
+
+// colorEnd ends the text colored started by colorStart, the text must not be empty
+func (p *renderer) colorEnd(c Color) {
+	if c.set && p.depth > ColorDepthNone {
+		p.b = append(p.b, "\x1b[0m"...)
+	}
+}
+
+// space separates the parts of the line
+func (p *renderer) space() {
+	if len(p.b) > 0 {
+		p.b = append(p.b, ' ')
+	}
+}
+
+// newline starts the line of the nesting level if the renderer indents
+func (p *renderer) newline(level int) {
+	if p.indent == "" {
+		return
+	}
+	p.b = append(p.b, '\n')
+	p.b = append(p.b, strings.Repeat(p.indent, level+1)...)
+}
+
+func (p *renderer) object(o object, level int) error {
+	punct := p.theme.Attrs
+	if len(o) == 0 {
+		p.color(punct, "{}")
+		return nil
+	}
+	p.color(punct, "{")
+	for i, m := range o {
+		if i > 0 {
+			p.color(punct, ",")
+		}
+		p.newline(level + 1)
+		k, err := json.Marshal(m.key)
+		if err != nil {
+			return err
+		}
+		sep := ":"
+		if p.indent != "" {
+			sep = ": "
+		}
+		if err := p.member(string(k)+sep, m, level+1); err != nil {
+			return err
+		}
+	}
+	p.newline(level)
+	p.color(punct, "}")
+	return nil
+}
+
+// member prints the key and the value of m, dimmed if the key is one of dimKeys
+func (p *renderer) member(key string, m member, level int) error {
+	if slices.Contains(p.dimKeys, m.key) {
+		theme := p.theme
+		p.theme = theme.dimmed()
+		defer func() { p.theme = theme }()
+	}
+	p.color(p.theme.Attrs, key)
+	return p.value(m.value, level)
+}
+
+func (p *renderer) value(v interface{}, level int) error {
+	switch v := v.(type) {
+	case object:
+		return p.object(v, level)
+	case collapsedGroup:
+		p.color(p.theme.valueColor(v), v.String())
+		return nil
+	case slog.LogValuer:
+		rv := slog.AnyValue(v).Resolve()
+		if rv.Kind() == slog.KindGroup {
+			return p.object(newObject(resolveAttrs(rv.Group()), false), level)
+		}
+		return p.value(rv.Any(), level)
+	case error:
+		s, err := json.Marshal(v.Error())
+		if err != nil {
+			return err
+		}
+		p.color(p.theme.valueColor(v), string(s))
+		return nil
+	}
+	if isCollection(v) {
+		return p.collection(reflect.ValueOf(v), level)
+	}
+	if f, ok := p.formats.format(v); ok {
+		s, err := json.Marshal(f)
+		if err != nil {
+			return err
+		}
+		p.color(p.theme.valueColor(v), string(s))
+		return nil
+	}
+	s, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if p.indent != "" && len(s) > 0 && (s[0] == '{' || s[0] == '[') {
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, s, strings.Repeat(p.indent, level+1), p.indent); err != nil {
+			return err
+		}
+		s = buf.Bytes()
+	}
+	p.color(p.theme.valueColor(v), string(s))
+	return nil
+}
+
+// isCollection reports whether v is a map or a slice printed by the renderer
+// itself, not by json.Marshal
+func isCollection(v interface{}) bool {
+	switch v.(type) {
+	case nil, []byte, json.Marshaler, encoding.TextMarshaler:
+		return false
+	}
+	switch reflect.TypeOf(v).Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array:
+		return true
+	}
+	return false
+}
+
+// collection prints the map, with the keys sorted, or the slice, the elements
+// printed as the attribute values, only the first maxItems of them
+func (p *renderer) collection(v reflect.Value, level int) error {
+	punct := p.theme.Attrs
+	if (v.Kind() == reflect.Map || v.Kind() == reflect.Slice) && v.IsNil() {
+		p.color(p.theme.Null, "null")
+		return nil
+	}
+	open, end := "[", "]"
+	var entries []mapEntry
+	if v.Kind() == reflect.Map {
+		open, end = "{", "}"
+		var err error
+		if entries, err = mapEntries(v); err != nil {
+			return err
+		}
+	}
+	n := v.Len()
+	if n == 0 {
+		p.color(punct, open+end)
+		return nil
+	}
+	shown, more := n, ""
+	if p.maxItems > 0 && n > p.maxItems {
+		shown, more = p.maxItems, fmt.Sprintf("…+%d more", n-p.maxItems)
+	}
+	p.color(punct, open)
+	for i := 0; i < shown; i++ {
+		if i > 0 {
+			p.color(punct, ",")
+		}
+		p.newline(level + 1)
+		var elem reflect.Value
+		if entries != nil {
+			k, err := json.Marshal(entries[i].key)
+			if err != nil {
+				return err
+			}
+			sep := ":"
+			if p.indent != "" {
+				sep = ": "
+			}
+			p.color(punct, string(k)+sep)
+			elem = entries[i].value
+		} else {
+			elem = v.Index(i)
+		}
+		if err := p.value(elem.Interface(), level+1); err != nil {
+			return err
+		}
+	}
+	if more != "" {
+		p.color(punct, ",")
+		p.newline(level + 1)
+		p.color(p.theme.Null, more)
+	}
+	p.newline(level)
+	p.color(punct, end)
+	return nil
+}
+
+type mapEntry struct {
+	key   string
+	value reflect.Value
+}
+
+// mapEntries returns the entries of the map sorted by key
+func mapEntries(v reflect.Value) ([]mapEntry, error) {
+	entries := make([]mapEntry, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		k, err := mapKey(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, mapEntry{k, iter.Value()})
+	}
+	slices.SortFunc(entries, func(a, b mapEntry) int { return strings.Compare(a.key, b.key) })
+	return entries, nil
+}
+
+// mapKey returns the map key as a string, as encoding/json does
+func mapKey(k reflect.Value) (string, error) {
+	if k.Kind() == reflect.String {
+		return k.String(), nil
+	}
+	if m, ok := k.Interface().(encoding.TextMarshaler); ok {
+		b, err := m.MarshalText()
+		return string(b), err
+	}
+	return fmt.Sprint(k.Interface()), nil
+}
+
+// valueColor returns the color of the value by its type, the Attrs color for
+// the types without a color of their own
+func (t *Theme) valueColor(v interface{}) Color {
+	var c Color
+	switch v.(type) {
+	case nil, collapsedGroup:
+		c = t.Null
+	case string:
+		c = t.String
+	case bool:
+		c = t.Bool
+	case time.Duration, time.Time:
+		c = t.Duration
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr, float32, float64:
+		c = t.Number
+	case error:
+		c = t.Error
+	}
+	if !c.set {
+		return t.Attrs
+	}
+	return c
+}
@@ -0,0 +1,116 @@
+package pretty
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// repeats counts the records of the output repeating the previous one
+type repeats struct {
+	prev   []byte // line of the previous record after the time column
+	count  int    // of the records of the line, 0 if there is no previous record
+	prefix []byte // icon and time column of the last repeat
+	color  Color  // of the counter
+	depth  ColorDepth
+	timer  *time.Timer // writes the counter after the timeout of the last record
+	gen    uint64      // of the timer, a fired callback of an earlier one does nothing
+	closed bool        // by Close, the timer is not armed again
+}
+
+// WithCollapseRepeats sets printing the consecutive records of the same line
+// after the time column once, followed by the line of their number, e.g.
+// "… ×37", written when a different record arrives, after the timeout
+// without records or by Flush. timeout <= 0 disables collapsing.
+func (h Handler) WithCollapseRepeats(timeout time.Duration) Handler {
+	h.CollapseRepeats = timeout
+	return h
+}
+
+// writeRepeated writes the line of the buffer taken from bufPool as write
+// does, unless it repeats the previous line after the key start, counting
+// the repeats instead
+func (o *output) writeRepeated(bp *[]byte, keyStart int, timeout time.Duration, c Color, depth ColorDepth) error {
+	line := *bp
+	key := line[keyStart:]
+	r := &o.repeats
+	o.repeatsMu.Lock()
+	defer o.repeatsMu.Unlock()
+	if r.count > 0 && bytes.Equal(r.prev, key) {
+		r.count++
+		r.prefix = append(r.prefix[:0], line[:keyStart]...)
+		r.color, r.depth = c, depth
+		o.armRepeatsLocked(timeout)
+		putBuf(bp)
+		return nil
+	}
+	err := o.writeRepeatsLocked()
+	r.prev = append(r.prev[:0], key...)
+	r.count = 1
+	o.armRepeatsLocked(timeout)
+	return errors.Join(err, o.write(bp))
+}
+
+// armRepeatsLocked restarts the timer writing the counter. The timer is
+// replaced rather than reset, so that the callback of the previous one which
+// has already fired and waits for repeatsMu sees the generation has changed.
+func (o *output) armRepeatsLocked(timeout time.Duration) {
+	r := &o.repeats
+	r.stopLocked()
+	if r.closed {
+		return
+	}
+	gen := r.gen
+	r.timer = time.AfterFunc(timeout, func() {
+		o.repeatsMu.Lock()
+		defer o.repeatsMu.Unlock()
+		if o.repeats.gen == gen {
+			_ = o.writeRepeatsLocked()
+		}
+	})
+}
+
+// stopLocked stops the timer and invalidates its callback if it has fired
+func (r *repeats) stopLocked() {
+	r.gen++
+	if r.timer != nil {
+		r.timer.Stop()
+		r.timer = nil
+	}
+}
+
+// closeRepeats writes the counter as flushRepeats does and stops the timer
+// for good
+func (o *output) closeRepeats() error {
+	o.repeatsMu.Lock()
+	defer o.repeatsMu.Unlock()
+	o.repeats.stopLocked()
+	o.repeats.closed = true
+	return o.writeRepeatsLocked()
+}
+
+// flushRepeats writes the number of the repeats of the previous record if
+// there are any and forgets the record
+func (o *output) flushRepeats() error {
+	o.repeatsMu.Lock()
+	defer o.repeatsMu.Unlock()
+	return o.writeRepeatsLocked()
+}
+
+func (o *output) writeRepeatsLocked() error {
+	r := &o.repeats
+	n := r.count
+	r.count = 0
+	if n < 2 {
+		return nil
+	}
+	bp := bufPool.Get().(*[]byte)
+	b := append((*bp)[:0], r.prefix...)
+	if len(b) > 0 {
+		b = append(b, ' ')
+	}
+	b = r.color.appendTo(b, r.depth, "… ×"+strconv.Itoa(n))
+	*bp = append(b, '\n')
+	return o.write(bp)
+}
@@ -0,0 +1,71 @@
+package pretty
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// SourceStyle is how the paths of the source files are displayed
+type SourceStyle int
+
+const (
+	SourceStyleBase    SourceStyle = iota // the file name, e.g. "pretty.go:42"
+	SourceStylePackage                    // the package directory and the file name, e.g. "pretty/pretty.go:42"
+	SourceStyleFull                       // the full path, e.g. "/home/me/slogx/pretty/pretty.go:42"
+	SourceStyleModule                     // the path in the module, e.g. "pretty/pretty.go:42", the full path outside modules
+)
+
+// moduleRoots caches the root directories of the modules of the source
+// directories, "" for the ones outside modules
+var moduleRoots sync.Map
+
+// formatSource returns the location of the source in the style of the handler
+// followed by the function name, e.g. "pretty.go:42.Handle"
+func (h Handler) formatSource(src *slog.Source) string {
+	var file string
+	switch h.SourceStyle {
+	case SourceStylePackage:
+		dir, name := filepath.Split(src.File)
+		file = filepath.Join(filepath.Base(dir), name)
+	case SourceStyleFull:
+		file = src.File
+	case SourceStyleModule:
+		file = src.File
+		if root := moduleRoot(filepath.Dir(src.File)); root != "" {
+			if rel, err := filepath.Rel(root, src.File); err == nil {
+				file = rel
+			}
+		}
+	default:
+		file = filepath.Base(src.File)
+	}
+	s := file + ":" + strconv.Itoa(src.Line)
+	if h.OmitSourceFunc {
+		return s
+	}
+	function := filepath.Base(src.Function)
+	if i := strings.IndexByte(function, '.'); i >= 0 {
+		function = function[i:]
+	}
+	return s + function
+}
+
+// moduleRoot returns the closest directory of dir or its parents with a go.mod
+// file, "" if there is none
+func moduleRoot(dir string) string {
+	if root, ok := moduleRoots.Load(dir); ok {
+		return root.(string)
+	}
+	var root string
+	if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+		root = dir
+	} else if parent := filepath.Dir(dir); parent != dir {
+		root = moduleRoot(parent)
+	}
+	moduleRoots.Store(dir, root)
+	return root
+}
@@ -0,0 +1,335 @@
+package pretty
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/mattn/go-isatty"
+)
+
+type (
+	// Theme is the colors of the parts of a record. The attribute values are
+	// colored by their type, the values of the types without a color by Attrs.
+	Theme struct {
+		Time     Color
+		Message  Color
+		Attrs    Color // keys and the values of other types
+		Source   Color
+		String   Color
+		Number   Color
+		Bool     Color
+		Null     Color
+		Duration Color // durations and times
+		Error    Color
+		Dim      Color            // attributes of Handler.DimKeys
+		Levels   map[Level]Color  // levels without a color are printed with the color of the closest lower level, or the lowest one
+		Icons    map[Level]string // icons prefixing the records if Handler.Icons is set, chosen as Levels
+	}
+
+	// Color is a foreground color printed with the escape sequences the terminal
+	// supports; the zero Color prints the text as is
+	Color struct {
+		set   bool
+		basic uint8 // SGR code of the 16 colors palette
+		rgb   [3]uint8
+		isRGB bool
+	}
+
+	// ColorDepth is the number of colors a terminal supports
+	ColorDepth int
+)
+
+const (
+	ColorDepthAuto ColorDepth = iota // detected by DetectColorDepth
+	ColorDepthNone                   // no colors
+	ColorDepth16
+	ColorDepth256
+	ColorDepthTrue // 24-bit colors
+)
+
+var (
+	// ThemeDark is the default theme, for dark terminal backgrounds
+	ThemeDark = Theme{
+		Time:     ANSI(37),
+		Message:  ANSI(36),
+		Attrs:    ANSI(37),
+		Source:   ANSI(32),
+		String:   ANSI(92),
+		Number:   ANSI(96),
+		Bool:     ANSI(95),
+		Null:     ANSI(90),
+		Duration: ANSI(94),
+		Error:    ANSI(91),
+		Dim:      ANSI(90),
+		Levels: map[Level]Color{
+			LevelTrace:      ANSI(90),
+			slog.LevelDebug: ANSI(35),
+			slog.LevelInfo:  ANSI(34),
+			slog.LevelWarn:  ANSI(33),
+			slog.LevelError: ANSI(31),
+			LevelFatal:      ANSI(91),
+		},
+		Icons: defaultIcons,
+	}
+
+	// ThemeLight is the theme for light terminal backgrounds
+	ThemeLight = Theme{
+		Time:     RGB(0x70, 0x70, 0x70),
+		Message:  RGB(0x00, 0x5f, 0x87),
+		Attrs:    RGB(0x30, 0x30, 0x30),
+		Source:   RGB(0x00, 0x87, 0x00),
+		String:   RGB(0x00, 0x5f, 0x00),
+		Number:   RGB(0x00, 0x5f, 0xaf),
+		Bool:     RGB(0x87, 0x00, 0xaf),
+		Null:     RGB(0x80, 0x80, 0x80),
+		Duration: RGB(0x00, 0x87, 0x87),
+		Error:    RGB(0xaf, 0x00, 0x00),
+		Dim:      RGB(0xb2, 0xb2, 0xb2),
+		Levels: map[Level]Color{
+			LevelTrace:      RGB(0x80, 0x80, 0x80),
+			slog.LevelDebug: RGB(0x87, 0x00, 0x87),
+			slog.LevelInfo:  RGB(0x00, 0x00, 0xd7),
+			slog.LevelWarn:  RGB(0xaf, 0x5f, 0x00),
+			slog.LevelError: RGB(0xd7, 0x00, 0x00),
+			LevelFatal:      RGB(0x87, 0x00, 0x00),
+		},
+		Icons: defaultIcons,
+	}
+
+	// ThemeMonochrome prints no colors
+	ThemeMonochrome = Theme{Icons: defaultIcons}
+
+	defaultIcons = map[Level]string{
+		LevelTrace:      "🟣",
+		slog.LevelDebug: "🔵",
+		slog.LevelInfo:  "🟢",
+		slog.LevelWarn:  "🟡",
+		slog.LevelError: "🔴",
+		LevelFatal:      "💀",
+	}
+)
+
+// ANSI returns the color of the SGR foreground code of the 16 colors palette, 30-37 or 90-97
+func ANSI(code uint8) Color {
+	return Color{set: true, basic: code}
+}
+
+// RGB returns the 24-bit color, approximated on terminals supporting less colors
+func RGB(r, g, b uint8) Color {
+	return Color{set: true, rgb: [3]uint8{r, g, b}, isRGB: true, basic: basicOf(r, g, b)}
+}
+
+// Sprint returns s colored for the depth
+func (c Color) Sprint(depth ColorDepth, s string) string {
+	if !c.set || depth <= ColorDepthNone || s == "" {
+		return s
+	}
+	return string(c.appendTo(nil, depth, s))
+}
+
+// appendTo appends s colored for the depth to b
+func (c Color) appendTo(b []byte, depth ColorDepth, s string) []byte {
+	if !c.set || depth <= ColorDepthNone || s == "" {
+		return append(b, s...)
+	}
+	b = c.appendStart(b, depth)
+	b = append(b, s...)
+	return append(b, "\x1b[0m"...)
+}
+
+// appendStart appends the escape sequence starting the color
+func (c Color) appendStart(b []byte, depth ColorDepth) []byte {
+	b = append(b, "\x1b["...)
+	switch {
+	case c.isRGB && depth >= ColorDepthTrue:
+		b = append(b, "38;2;"...)
+		b = strconv.AppendInt(b, int64(c.rgb[0]), 10)
+		b = append(b, ';')
+		b = strconv.AppendInt(b, int64(c.rgb[1]), 10)
+		b = append(b, ';')
+		b = strconv.AppendInt(b, int64(c.rgb[2]), 10)
+	case c.isRGB && depth == ColorDepth256:
+		b = append(b, "38;5;"...)
+		b = strconv.AppendInt(b, int64(index256(c.rgb)), 10)
+	default:
+		b = strconv.AppendInt(b, int64(c.basic), 10)
+	}
+	return append(b, 'm')
+}
+
+// ColorEnabled reports whether colors are printed to w by the conventional
+// environment variables: NO_COLOR disables colors, FORCE_COLOR and
+// CLICOLOR_FORCE enable them even if w is not a terminal, CLICOLOR=0 and
+// TERM=dumb disable them. Otherwise colors are enabled if w is a terminal.
+func ColorEnabled(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	if envForced("FORCE_COLOR") || envForced("CLICOLOR_FORCE") {
+		return true
+	}
+	if os.Getenv("CLICOLOR") == "0" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return isTerminal(w)
+}
+
+// DetectColorDepth returns the color depth of w, ColorDepthNone if colors are
+// not enabled by ColorEnabled, else by FORCE_COLOR levels 1-3 or the
+// conventional environment variables COLORTERM and TERM
+func DetectColorDepth(w io.Writer) ColorDepth {
+	if !ColorEnabled(w) {
+		return ColorDepthNone
+	}
+	return envColorDepth()
+}
+
+// envColorDepth returns the color depth by the environment, at least ColorDepth16
+func envColorDepth() ColorDepth {
+	switch os.Getenv("FORCE_COLOR") {
+	case "2":
+		return ColorDepth256
+	case "3":
+		return ColorDepthTrue
+	}
+	switch ct := os.Getenv("COLORTERM"); {
+	case ct == "truecolor" || ct == "24bit":
+		return ColorDepthTrue
+	case strings.Contains(os.Getenv("TERM"), "256color"):
+		return ColorDepth256
+	}
+	return ColorDepth16
+}
+
+// envForced reports whether the environment variable is set to a value other than 0
+func envForced(key string) bool {
+	v, ok := os.LookupEnv(key)
+	return ok && v != "" && v != "0" && v != "false"
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(interface{ Fd() uintptr })
+	if !ok {
+		return false
+	}
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
+
+// level returns the color of the level
+func (t *Theme) level(l Level) Color {
+	c, _ := closestLevel(t.Levels, l)
+	return c
+}
+
+// dimmed returns the theme printing the attributes in the Dim color
+func (t *Theme) dimmed() *Theme {
+	d := *t
+	d.Attrs, d.String, d.Number, d.Bool, d.Null, d.Duration, d.Error = t.Dim, t.Dim, t.Dim, t.Dim, t.Dim, t.Dim, t.Dim
+	return &d
+}
+
+// icon returns the icon of the level padded to the width of the widest icon
+func (t *Theme) icon(l Level) string {
+	icon, _ := closestLevel(t.Icons, l)
+	width := 0
+	for _, s := range t.Icons {
+		width = max(width, displayWidth(s))
+	}
+	return icon + strings.Repeat(" ", width-displayWidth(icon))
+}
+
+// closestLevel returns the value of the level, or of the closest lower level,
+// or of the lowest level
+func closestLevel[T any](m map[Level]T, l Level) (T, bool) {
+	if v, ok := m[l]; ok {
+		return v, true
+	}
+	var (
+		lower, lowest     T
+		found, anyFound   bool
+		lowerAt, lowestAt Level
+	)
+	for x, v := range m {
+		if x <= l && (!found || x > lowerAt) {
+			lower, found, lowerAt = v, true, x
+		}
+		if !anyFound || x < lowestAt {
+			lowest, anyFound, lowestAt = v, true, x
+		}
+	}
+	if found {
+		return lower, true
+	}
+	return lowest, anyFound
+}
+
+// displayWidth returns the number of terminal columns of s, counting the
+// emoji and the east asian wide characters as two columns
+func displayWidth(s string) int {
+	n := 0
+	for _, r := range s {
+		switch {
+		case r == 0x200d || r >= 0xfe00 && r <= 0xfe0f || unicode.Is(unicode.Mn, r):
+			// zero width joiner, variation selectors and combining marks
+		case r >= 0x1100 && r <= 0x115f, r >= 0x2e80 && r <= 0xa4cf, r >= 0xac00 && r <= 0xd7a3,
+			r >= 0xf900 && r <= 0xfaff, r >= 0xff00 && r <= 0xff60, r >= 0xffe0 && r <= 0xffe6,
+			r >= 0x1f300 && r <= 0x1faff, r >= 0x20000 && r <= 0x3fffd:
+			n += 2
+		default:
+			n++
+		}
+	}
+	return n
+}
+
+// visibleWidth returns the display width of the text without the color escape sequences
+func visibleWidth(b []byte) int {
+	n := 0
+	for len(b) > 0 {
+		i := bytes.Index(b, []byte("\x1b["))
+		if i < 0 {
+			return n + displayWidth(string(b))
+		}
+		n += displayWidth(string(b[:i]))
+		end := bytes.IndexByte(b[i:], 'm')
+		if end < 0 {
+			return n
+		}
+		b = b[i+end+1:]
+	}
+	return n
+}
+
+// index256 returns the closest color of the 6x6x6 cube of the 256 colors palette
+func index256(rgb [3]uint8) uint8 {
+	q := func(v uint8) uint8 {
+		if v < 48 {
+			return 0
+		}
+		if v < 115 {
+			return 1
+		}
+		return (v - 35) / 40
+	}
+	return 16 + 36*q(rgb[0]) + 6*q(rgb[1]) + q(rgb[2])
+}
+
+// basicOf returns the SGR code of the closest color of the 16 colors palette
+func basicOf(r, g, b uint8) uint8 {
+	bit := func(v uint8) uint8 {
+		if v >= 0x80 {
+			return 1
+		}
+		return 0
+	}
+	code := 30 + bit(r) + bit(g)<<1 + bit(b)<<2
+	if max(r, g, b) >= 0xd0 {
+		code += 60 // bright
+	}
+	return code
+}
@@ -0,0 +1,145 @@
+package pretty
+
+import (
+	"bytes"
+	"fmt"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// TimeMode is what the time column of the records shows
+type TimeMode int
+
+const (
+	TimeModeAbsolute   TimeMode = iota // the time of the record in the time layout
+	TimeModeSinceStart                 // the time since the process start, e.g. "1.204s"
+	TimeModeDelta                      // the time since the previous record, e.g. "+12.3ms"
+)
+
+// RepeatedTimeStyle is how the time of a record equal to the time of the
+// previous record of the output is displayed
+type RepeatedTimeStyle int
+
+const (
+	RepeatedTimeStyleShown RepeatedTimeStyle = iota // as any other time
+	RepeatedTimeStyleBlank                          // as spaces of the same width
+	RepeatedTimeStyleDim                            // in the Dim color of the theme
+)
+
+// Time layouts of the precisions
+const (
+	TimeLayoutSeconds = "15:04:05"
+	TimeLayoutMillis  = "15:04:05.000"
+	TimeLayoutMicros  = "15:04:05.000000"
+)
+
+// processStart is the time TimeModeSinceStart counts from without Clock
+var processStart = time.Now()
+
+// timeState is the times of the records of the relative time modes, unix
+// nanoseconds shared by the handlers derived from the one of WithTimeMode
+type timeState struct {
+	start atomic.Int64 // of the first record, TimeModeSinceStart counts from it with Clock
+	last  atomic.Int64 // of the previous record
+}
+
+// elapsedWidth is the width the elapsed times are right-aligned to
+const elapsedWidth = 8
+
+// WithTimeMode sets what the time column shows, e.g. TimeModeDelta to see the
+// latency between the records. The time layout is used only by
+// TimeModeAbsolute, and the records are printed without time if it is empty.
+// The handlers derived from the returned one share the previous record time
+// of TimeModeDelta. With Clock the relative modes measure the times of the
+// Clock, TimeModeSinceStart from the first record, for deterministic output.
+func (h Handler) WithTimeMode(m TimeMode) Handler {
+	h.TimeMode = m
+	h.times = new(timeState)
+	return h
+}
+
+// WithUTC sets printing the times of the records in UTC instead of the local time
+func (h Handler) WithUTC(v bool) Handler {
+	h.UTC = v
+	return h
+}
+
+// WithRepeatedTime sets how the time of a record is displayed if it is the
+// time of the previous record of the output at the precision of the time
+// layout, e.g. RepeatedTimeStyleBlank to reduce the noise of bursts of records
+func (h Handler) WithRepeatedTime(s RepeatedTimeStyle) Handler {
+	h.RepeatedTime = s
+	return h
+}
+
+// appendTime appends the time of the record to be written to the output in
+// the time mode of the handler
+func (h Handler) appendTime(p *renderer, out *output, t time.Time) {
+	if h.TimeMode != TimeModeAbsolute && h.Clock != nil {
+		t = h.Clock.Now()
+	}
+	switch h.TimeMode {
+	case TimeModeSinceStart:
+		start := processStart
+		if h.Clock != nil && h.times != nil {
+			h.times.start.CompareAndSwap(0, t.UnixNano())
+			start = time.Unix(0, h.times.start.Load())
+		}
+		p.color(p.theme.Time, fmt.Sprintf("%*s", elapsedWidth, formatElapsed(t.Sub(start))))
+	case TimeModeDelta:
+		var d time.Duration
+		if h.times != nil {
+			if prev := h.times.last.Swap(t.UnixNano()); prev != 0 {
+				d = time.Duration(t.UnixNano() - prev)
+			}
+		}
+		p.color(p.theme.Time, fmt.Sprintf("%*s", elapsedWidth, "+"+formatElapsed(d)))
+	default:
+		if h.UTC {
+			t = t.UTC()
+		}
+		var buf [64]byte
+		ts := t.AppendFormat(buf[:0], h.TimeLayout)
+		c := p.theme.Time
+		if h.RepeatedTime != RepeatedTimeStyleShown && out.repeatsTime(ts) {
+			if h.RepeatedTime == RepeatedTimeStyleBlank {
+				for range utf8.RuneCount(ts) {
+					p.b = append(p.b, ' ')
+				}
+				return
+			}
+			if p.theme.Dim.set {
+				c = p.theme.Dim
+			}
+		}
+		p.colorStart(c)
+		p.b = append(p.b, ts...)
+		p.colorEnd(c)
+	}
+}
+
+// repeatsTime reports whether the time column is the one of the previous
+// record of the output, remembering it for the next record
+func (o *output) repeatsTime(ts []byte) bool {
+	o.timeMu.Lock()
+	defer o.timeMu.Unlock()
+	if o.prevTime != nil && bytes.Equal(o.prevTime, ts) {
+		return true
+	}
+	o.prevTime = append(o.prevTime[:0], ts...)
+	return false
+}
+
+// formatElapsed returns the duration rounded to the precision of its magnitude
+func formatElapsed(d time.Duration) string {
+	switch a := d.Abs(); {
+	case a >= time.Second:
+		d = d.Round(time.Millisecond)
+	case a >= time.Millisecond:
+		d = d.Round(100 * time.Microsecond)
+	default:
+		d = d.Round(time.Microsecond)
+	}
+	return d.String()
+}
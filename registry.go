@@ -0,0 +1,96 @@
+package slogx
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// Directives are logging settings of components, applied to Registry.
+//
+//	{
+//	  "levels":   {"*": "INFO", "db": "DEBUG"},
+//	  "sampling": {"http": 0.1},
+//	  "redact":   {"secrets": true}
+//	}
+type Directives struct {
+	Levels   map[string]slog.Level `json:"levels,omitempty"`   // component name to minimum level
+	Sampling map[string]float64    `json:"sampling,omitempty"` // component name to fraction of records kept
+	Redact   map[string]bool       `json:"redact,omitempty"`   // redaction toggle name to state
+}
+
+// Registry holds the named logging controls of components: levels for
+// handler options, rates for SamplingHandler and redaction toggles, so they
+// can be changed together at runtime, e.g. by WatchConfig.
+// Controls are created on first use with the defaults: Info level,
+// all records kept, redaction enabled.
+type Registry struct {
+	mu       sync.Mutex
+	levels   map[string]*slog.LevelVar
+	sampling map[string]*SampleRate
+	redact   map[string]*atomic.Bool
+}
+
+// DefaultRegistry is the process-wide Registry.
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry creates an empty Registry
+func NewRegistry() *Registry {
+	return &Registry{
+		levels:   make(map[string]*slog.LevelVar),
+		sampling: make(map[string]*SampleRate),
+		redact:   make(map[string]*atomic.Bool),
+	}
+}
+
+// Level returns the level of the component, to be used as slog.HandlerOptions.Level:
+//
+//	h := slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slogx.DefaultRegistry.Level("db")})
+func (r *Registry) Level(name string) *slog.LevelVar {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	v, ok := r.levels[name]
+	if !ok {
+		v = new(slog.LevelVar)
+		r.levels[name] = v
+	}
+	return v
+}
+
+// SampleRate returns the sampling rate of the component, to be used with NewSamplingHandler.
+func (r *Registry) SampleRate(name string) *SampleRate {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	v, ok := r.sampling[name]
+	if !ok {
+		v = NewSampleRate(1)
+		r.sampling[name] = v
+	}
+	return v
+}
+
+// Redaction returns the redaction toggle of the name, to be used as SecretsOptions.Toggle.
+func (r *Registry) Redaction(name string) *atomic.Bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	v, ok := r.redact[name]
+	if !ok {
+		v = new(atomic.Bool)
+		v.Store(true)
+		r.redact[name] = v
+	}
+	return v
+}
+
+// Apply sets the controls named in d. The controls not named keep their values.
+func (r *Registry) Apply(d Directives) {
+	for name, level := range d.Levels {
+		r.Level(name).Set(level)
+	}
+	for name, fraction := range d.Sampling {
+		r.SampleRate(name).Set(fraction)
+	}
+	for name, on := range d.Redact {
+		r.Redaction(name).Store(on)
+	}
+}
@@ -0,0 +1,167 @@
+package slogx
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRegistryApply(t *testing.T) {
+	reg := NewRegistry()
+	if reg.Level("db").Level() != slog.LevelInfo || reg.SampleRate("http").Fraction() != 1 || !reg.Redaction("secrets").Load() {
+		t.Fatal("unexpected defaults")
+	}
+	level := reg.Level("db")
+
+	var d Directives
+	if err := json.Unmarshal([]byte(`{"levels":{"db":"DEBUG"},"sampling":{"http":0.1},"redact":{"secrets":false}}`), &d); err != nil {
+		t.Fatal(err)
+	}
+	reg.Apply(d)
+	if level.Level() != slog.LevelDebug || reg.SampleRate("http").Fraction() != 0.1 || reg.Redaction("secrets").Load() {
+		t.Fatal("directives not applied")
+	}
+	reg.Apply(Directives{Levels: map[string]slog.Level{"api": slog.LevelWarn}})
+	if level.Level() != slog.LevelDebug || reg.Level("api").Level() != slog.LevelWarn {
+		t.Fatal("controls not named changed")
+	}
+}
+
+func TestSamplingHandler(t *testing.T) {
+	buf := new(bytes.Buffer)
+	rate := NewSampleRate(0)
+	logger := slog.New(NewSamplingHandler(routeTextHandler(buf), rate)).With("app", "api")
+
+	logger.Info("dropped")
+	logger.Warn("kept")
+	rate.Set(2)
+	logger.Info("all")
+	if rate.Fraction() != 1 {
+		t.Fatalf("fraction %v, expected clamped to 1", rate.Fraction())
+	}
+	if buf.String() != "msg=kept app=api\nmsg=all app=api\n" {
+		t.Fatalf("got\n%s", buf)
+	}
+
+	rate.Set(0.5)
+	h := NewSamplingHandler(slog.NewTextHandler(io.Discard, nil), rate)
+	if !h.Enabled(context.Background(), slog.LevelInfo) {
+		t.Fatal("disabled with a non-zero rate")
+	}
+	rate.Set(-1)
+	if h.Enabled(context.Background(), slog.LevelInfo) || !h.Enabled(context.Background(), slog.LevelError) {
+		t.Fatal("unexpected Enabled with a zero rate")
+	}
+}
+
+func TestWatchConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logging.json")
+	if err := os.WriteFile(path, []byte(`{"levels":{"db":"DEBUG"}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	reg := NewRegistry()
+	buf := new(syncBuffer)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		WatchConfig(ctx, FileSource(path), 10*time.Millisecond, reg, slog.New(NewLogfmtHandler(buf, nil)))
+		close(done)
+	}()
+
+	waitFor := func(cond func() bool) {
+		t.Helper()
+		for i := 0; !cond(); i++ {
+			if i == 100 {
+				t.Fatalf("timeout, logged:\n%s", buf)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitFor(func() bool { return reg.Level("db").Level() == slog.LevelDebug })
+	if err := os.WriteFile(path, []byte(`{"levels":`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(func() bool { return strings.Contains(buf.String(), "parse logging directives") })
+	if err := os.WriteFile(path, []byte(`{"levels":{"db":"ERROR"}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(func() bool { return reg.Level("db").Level() == slog.LevelError })
+	cancel()
+	<-done
+}
+
+func TestWatchConfigZeroInterval(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logging.json")
+	if err := os.WriteFile(path, []byte(`{"levels":{"db":"DEBUG"}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	reg := NewRegistry()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		WatchConfig(ctx, FileSource(path), 0, reg, slog.New(NewLogfmtHandler(io.Discard, nil)))
+		close(done)
+	}()
+	for i := 0; reg.Level("db").Level() != slog.LevelDebug; i++ {
+		if i == 100 {
+			t.Fatal("directives not applied")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+}
+
+func TestConsulSource(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/kv/app/logging" || r.URL.RawQuery != "raw" || r.Header.Get("X-Consul-Token") != "token" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = io.WriteString(w, `{"levels":{"*":"WARN"}}`)
+	}))
+	defer ts.Close()
+
+	b, err := ConsulSource{Addr: ts.URL + "/", Key: "/app/logging", Token: "token"}.Load(context.Background())
+	if err != nil || string(b) != `{"levels":{"*":"WARN"}}` {
+		t.Fatalf("got %s, %v", b, err)
+	}
+	if _, err := (ConsulSource{Addr: ts.URL, Key: "other"}).Load(context.Background()); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestEtcdSource(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct{ Key string }
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || r.URL.Path != "/v3/kv/range" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		if key, _ := base64.StdEncoding.DecodeString(req.Key); string(key) != "app/logging" {
+			_, _ = io.WriteString(w, `{}`)
+			return
+		}
+		value := base64.StdEncoding.EncodeToString([]byte(`{"sampling":{"http":0.5}}`))
+		_, _ = io.WriteString(w, `{"kvs":[{"value":"`+value+`"}]}`)
+	}))
+	defer ts.Close()
+
+	b, err := EtcdSource{Endpoint: ts.URL, Key: "app/logging"}.Load(context.Background())
+	if err != nil || string(b) != `{"sampling":{"http":0.5}}` {
+		t.Fatalf("got %s, %v", b, err)
+	}
+	if _, err := (EtcdSource{Endpoint: ts.URL, Key: "other"}).Load(context.Background()); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package slogx
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+type (
+	// ConfigSource loads the JSON encoded Directives.
+	ConfigSource interface {
+		Load(ctx context.Context) ([]byte, error)
+	}
+
+	// FileSource loads directives from the file path.
+	FileSource string
+
+	// ConsulSource loads directives from a Consul KV key.
+	ConsulSource struct {
+		Addr   string // http://127.0.0.1:8500 by default
+		Key    string
+		Token  string
+		Client *http.Client // http.DefaultClient by default
+	}
+
+	// EtcdSource loads directives from an etcd v3 key, with the JSON gateway of etcd.
+	EtcdSource struct {
+		Endpoint string // http://127.0.0.1:2379 by default
+		Key      string
+		Client   *http.Client // http.DefaultClient by default
+	}
+)
+
+// WatchConfig loads directives from src every interval and applies them to
+// reg when they change, until ctx is done. Failures are logged to logger,
+// or slog.Default if it is nil, and the previous directives stay in effect.
+// The interval is 30s if not positive.
+//
+//	go slogx.WatchConfig(ctx, slogx.FileSource("/etc/app/logging.json"), 30*time.Second, slogx.DefaultRegistry, nil)
+func WatchConfig(ctx context.Context, src ConfigSource, interval time.Duration, reg *Registry, logger *slog.Logger) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var prev []byte
+	for {
+		if b, err := src.Load(ctx); err != nil {
+			if ctx.Err() == nil {
+				logger.WarnContext(ctx, "load logging directives", "error", err)
+			}
+		} else if !bytes.Equal(b, prev) {
+			var d Directives
+			if err := json.Unmarshal(b, &d); err != nil {
+				logger.WarnContext(ctx, "parse logging directives", "error", err)
+			} else {
+				reg.Apply(d)
+				logger.InfoContext(ctx, "logging directives applied")
+			}
+			prev = b
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (f FileSource) Load(context.Context) ([]byte, error) {
+	return os.ReadFile(string(f))
+}
+
+func (s ConsulSource) Load(ctx context.Context) ([]byte, error) {
+	addr := s.Addr
+	if addr == "" {
+		addr = "http://127.0.0.1:8500"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		strings.TrimSuffix(addr, "/")+"/v1/kv/"+url.PathEscape(strings.TrimPrefix(s.Key, "/"))+"?raw", nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.Token != "" {
+		req.Header.Set("X-Consul-Token", s.Token)
+	}
+	return doConfigRequest(s.Client, req)
+}
+
+func (s EtcdSource) Load(ctx context.Context) ([]byte, error) {
+	endpoint := s.Endpoint
+	if endpoint == "" {
+		endpoint = "http://127.0.0.1:2379"
+	}
+	body, _ := json.Marshal(map[string]string{
+		"key": base64.StdEncoding.EncodeToString([]byte(s.Key)),
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		strings.TrimSuffix(endpoint, "/")+"/v3/kv/range", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	b, err := doConfigRequest(s.Client, req)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Kvs []struct {
+			Value string `json:"value"`
+		} `json:"kvs"`
+	}
+	if err := json.Unmarshal(b, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, fmt.Errorf("etcd: key %q not found", s.Key)
+	}
+	return base64.StdEncoding.DecodeString(resp.Kvs[0].Value)
+}
+
+func doConfigRequest(client *http.Client, req *http.Request) ([]byte, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s %s: %s", req.Method, req.URL.Redacted(), resp.Status)
+	}
+	return b, nil
+}
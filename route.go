@@ -0,0 +1,172 @@
+package slogx
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+type (
+	// RouteHandler routes records to child handlers by the value of an
+	// attribute, e.g. tenant_id to per-tenant sinks. The attribute is looked up
+	// in the record, which includes the slogctx fields when the handler is
+	// wrapped by slogctx.Handler, then in the attributes of WithAttrs.
+	// Records without a registered route go to the default handler, if any.
+	// Routes can be changed at any time, also for the handlers derived by
+	// WithAttrs and WithGroup.
+	RouteHandler struct {
+		key   string
+		table *routeTable
+		state attrState
+		cache *sync.Map // route value to *routeCacheEntry
+	}
+
+	routeTable struct {
+		mu      sync.RWMutex
+		routes  map[string]slog.Handler
+		def     slog.Handler
+		version uint64
+	}
+
+	// routeCacheEntry is a child handler with the WithAttrs/WithGroup calls of
+	// the RouteHandler applied.
+	routeCacheEntry struct {
+		version uint64
+		handler slog.Handler
+	}
+)
+
+var _ slog.Handler = (*RouteHandler)(nil)
+
+// defaultRoute is the cache key of the default handler.
+const defaultRoute = "\x00default"
+
+// NewRouteHandler creates RouteHandler routing by the attribute key.
+// The default handler may be nil to discard records without a route.
+func NewRouteHandler(key string, defaultHandler slog.Handler) *RouteHandler {
+	return &RouteHandler{
+		key: key,
+		table: &routeTable{
+			routes: make(map[string]slog.Handler),
+			def:    defaultHandler,
+		},
+		cache: new(sync.Map),
+	}
+}
+
+// SetRoute routes the records with the attribute value to the handler.
+// A nil handler removes the route.
+func (h *RouteHandler) SetRoute(value string, handler slog.Handler) {
+	h.table.mu.Lock()
+	defer h.table.mu.Unlock()
+	if handler == nil {
+		delete(h.table.routes, value)
+	} else {
+		h.table.routes[value] = handler
+	}
+	h.table.version++
+}
+
+// Unwrap returns the default handler and the handlers of all the routes.
+func (h *RouteHandler) Unwrap() []slog.Handler {
+	h.table.mu.RLock()
+	defer h.table.mu.RUnlock()
+	xs := make([]slog.Handler, 0, len(h.table.routes)+1)
+	if h.table.def != nil {
+		xs = append(xs, h.table.def)
+	}
+	for _, x := range h.table.routes {
+		xs = append(xs, x)
+	}
+	return xs
+}
+
+// Enabled reports whether any of the child handlers is enabled for the level.
+func (h *RouteHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	h.table.mu.RLock()
+	defer h.table.mu.RUnlock()
+	if h.table.def != nil && h.table.def.Enabled(ctx, level) {
+		return true
+	}
+	for _, x := range h.table.routes {
+		if x.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *RouteHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.state = h.state.withAttrs(attrs)
+	h2.cache = new(sync.Map)
+	return &h2
+}
+
+func (h *RouteHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.state = h.state.withGroup(name)
+	h2.cache = new(sync.Map)
+	return &h2
+}
+
+func (h *RouteHandler) Handle(ctx context.Context, r slog.Record) error {
+	target := h.child(h.routeValue(r))
+	if target == nil || !target.Enabled(ctx, r.Level) {
+		return nil
+	}
+	return target.Handle(ctx, r)
+}
+
+func (h *RouteHandler) routeValue(r slog.Record) string {
+	value, found := "", false
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Key == h.key {
+			value, found = a.Value.Resolve().String(), true
+			return false
+		}
+		return true
+	})
+	if found {
+		return value
+	}
+	for _, g := range h.state.goas {
+		if g.group != "" {
+			break
+		}
+		for _, a := range g.attrs {
+			if a.Key == h.key {
+				value = a.Value.Resolve().String()
+			}
+		}
+	}
+	return value
+}
+
+// child returns the handler of the route value, with the WithAttrs/WithGroup
+// calls applied, or nil if there is no route and no default handler.
+func (h *RouteHandler) child(value string) slog.Handler {
+	h.table.mu.RLock()
+	version := h.table.version
+	x, ok := h.table.routes[value]
+	if !ok {
+		x, value = h.table.def, defaultRoute
+	}
+	h.table.mu.RUnlock()
+	if x == nil {
+		return nil
+	}
+
+	if e, ok := h.cache.Load(value); ok && e.(*routeCacheEntry).version == version {
+		return e.(*routeCacheEntry).handler
+	}
+	for _, g := range h.state.goas {
+		if g.group != "" {
+			x = x.WithGroup(g.group)
+		} else {
+			x = x.WithAttrs(g.attrs)
+		}
+	}
+	h.cache.Store(value, &routeCacheEntry{version: version, handler: x})
+	return x
+}
@@ -0,0 +1,71 @@
+package slogx
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/fpawel/slogx/slogctx"
+)
+
+func routeTextHandler(buf *bytes.Buffer) slog.Handler {
+	return slog.NewTextHandler(buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if (a.Key == slog.TimeKey || a.Key == slog.LevelKey) && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+}
+
+func TestRouteHandler(t *testing.T) {
+	var a, b, def bytes.Buffer
+	h := NewRouteHandler("tenant", routeTextHandler(&def))
+	h.SetRoute("a", routeTextHandler(&a))
+	logger := slog.New(h)
+
+	logger.Info("x", "tenant", "a")
+	logger.Info("x", "tenant", "b")
+	tenantB := logger.With("tenant", "b").WithGroup("req")
+	h.SetRoute("b", routeTextHandler(&b)) // also for the derived handlers
+	tenantB.Info("y", "n", 1)
+	slog.New(slogctx.NewHandler(h)).InfoContext(slogctx.WithValues(context.Background(), "tenant", "a"), "ctx")
+	h.SetRoute("a", nil)
+	logger.Info("removed", "tenant", "a")
+
+	for _, tt := range []struct {
+		name     string
+		buf      *bytes.Buffer
+		expected string
+	}{
+		{"a", &a, "msg=x tenant=a\nmsg=ctx tenant=a\n"},
+		{"b", &b, "msg=y tenant=b req.n=1\n"},
+		{"default", &def, "msg=x tenant=b\nmsg=removed tenant=a\n"},
+	} {
+		if tt.buf.String() != tt.expected {
+			t.Errorf("%s:\n%s\nexpected\n%s", tt.name, tt.buf, tt.expected)
+		}
+	}
+	if n := len(h.Unwrap()); n != 2 {
+		t.Fatalf("%d handlers unwrapped, expected 2", n)
+	}
+}
+
+func TestRouteHandlerEnabled(t *testing.T) {
+	h := NewRouteHandler("tenant", nil)
+	if h.Enabled(context.Background(), slog.LevelError) {
+		t.Fatal("enabled without routes")
+	}
+	h.SetRoute("a", slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	if h.Enabled(context.Background(), slog.LevelInfo) || !h.Enabled(context.Background(), slog.LevelWarn) {
+		t.Fatal("unexpected Enabled")
+	}
+	// records without a route and no default handler are discarded
+	if err := h.Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelError, "x", 0)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,75 @@
+package slogx
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"sync/atomic"
+)
+
+// SampleRate is the fraction of records kept by SamplingHandler, safe to
+// change while logging.
+type SampleRate struct {
+	bits atomic.Uint64
+}
+
+// NewSampleRate creates SampleRate with the fraction in [0, 1]
+func NewSampleRate(fraction float64) *SampleRate {
+	r := new(SampleRate)
+	r.Set(fraction)
+	return r
+}
+
+// Set sets the fraction of records kept, clamped to [0, 1].
+func (r *SampleRate) Set(fraction float64) {
+	r.bits.Store(math.Float64bits(min(max(fraction, 0), 1)))
+}
+
+// Fraction returns the fraction of records kept.
+func (r *SampleRate) Fraction() float64 {
+	return math.Float64frombits(r.bits.Load())
+}
+
+// SamplingHandler passes a random sample of the records below Warn level to
+// the inner handler. Warn and Error records are always kept.
+type SamplingHandler struct {
+	inner slog.Handler
+	rate  *SampleRate
+}
+
+var _ slog.Handler = (*SamplingHandler)(nil)
+
+// NewSamplingHandler creates SamplingHandler keeping the rate of records
+func NewSamplingHandler(inner slog.Handler, rate *SampleRate) *SamplingHandler {
+	return &SamplingHandler{inner: inner, rate: rate}
+}
+
+// Unwrap returns the inner handler.
+func (h *SamplingHandler) Unwrap() slog.Handler {
+	return h.inner
+}
+
+func (h *SamplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if level < slog.LevelWarn && h.rate.Fraction() == 0 {
+		return false
+	}
+	return h.inner.Enabled(ctx, level)
+}
+
+func (h *SamplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level < slog.LevelWarn {
+		if f := h.rate.Fraction(); f < 1 && rand.Float64() >= f {
+			return nil
+		}
+	}
+	return h.inner.Handle(ctx, r)
+}
+
+func (h *SamplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &SamplingHandler{inner: h.inner.WithAttrs(attrs), rate: h.rate}
+}
+
+func (h *SamplingHandler) WithGroup(name string) slog.Handler {
+	return &SamplingHandler{inner: h.inner.WithGroup(name), rate: h.rate}
+}
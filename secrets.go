@@ -0,0 +1,232 @@
+package slogx
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"regexp"
+	"sync"
+	"sync/atomic"
+)
+
+// Kinds of secrets detected by SecretsHandler.
+const (
+	SecretAWSKey      = "aws_key"
+	SecretJWT         = "jwt"
+	SecretCreditCard  = "credit_card"
+	SecretHighEntropy = "high_entropy"
+)
+
+type (
+	// SecretsOptions configures SecretsHandler. Zero values select the defaults.
+	SecretsOptions struct {
+		Mask        string  // replaces the detected secrets, "[REDACTED]" by default
+		MinEntropy  float64 // bits per character of high-entropy tokens, 4 by default, negative to disable
+		MinTokenLen int     // length of high-entropy tokens, 20 by default
+		ScanMessage bool    // also scan the record message
+
+		// Toggle switches masking of records at runtime, e.g. Registry.Redaction;
+		// masking is always on if nil. Attributes of WithAttrs are masked regardless.
+		Toggle *atomic.Bool
+	}
+
+	// SecretsHandler masks secrets found in string attribute values and in
+	// the text of errors and fmt.Stringer values, such as AWS access keys,
+	// JWTs, credit card numbers and high-entropy tokens, before passing records to the inner handler. It is a safety net for
+	// secrets logged by mistake, not a replacement for omitting them.
+	SecretsHandler struct {
+		inner    slog.Handler
+		opts     SecretsOptions
+		counters *secretCounters
+	}
+
+	secretCounters struct {
+		m sync.Map // kind to *atomic.Uint64
+	}
+)
+
+var _ slog.Handler = (*SecretsHandler)(nil)
+
+var (
+	reAWSKey     = regexp.MustCompile(`\b(?:AKIA|ASIA|AGPA|AIDA|AROA|ANPA|ANVA|AIPA)[0-9A-Z]{16}\b`)
+	reJWT        = regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)
+	reCreditCard = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+	reToken      = regexp.MustCompile(`[A-Za-z0-9+/=_-]+`)
+)
+
+// NewSecretsHandler creates SecretsHandler wrapping inner
+func NewSecretsHandler(inner slog.Handler, opts *SecretsOptions) *SecretsHandler {
+	h := &SecretsHandler{
+		inner:    inner,
+		counters: new(secretCounters),
+	}
+	if opts != nil {
+		h.opts = *opts
+	}
+	if h.opts.Mask == "" {
+		h.opts.Mask = "[REDACTED]"
+	}
+	if h.opts.MinEntropy == 0 {
+		h.opts.MinEntropy = 4
+	}
+	if h.opts.MinTokenLen <= 0 {
+		h.opts.MinTokenLen = 20
+	}
+	return h
+}
+
+// Unwrap returns the inner handler.
+func (h *SecretsHandler) Unwrap() slog.Handler {
+	return h.inner
+}
+
+// Detections returns the number of secrets masked so far by kind.
+func (h *SecretsHandler) Detections() map[string]uint64 {
+	m := make(map[string]uint64)
+	h.counters.m.Range(func(k, v any) bool {
+		m[k.(string)] = v.(*atomic.Uint64).Load()
+		return true
+	})
+	return m
+}
+
+func (h *SecretsHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+func (h *SecretsHandler) Handle(ctx context.Context, r slog.Record) error {
+	if h.opts.Toggle != nil && !h.opts.Toggle.Load() {
+		return h.inner.Handle(ctx, r)
+	}
+	msg := r.Message
+	if h.opts.ScanMessage {
+		msg = h.mask(msg)
+	}
+	out := slog.NewRecord(r.Time, r.Level, msg, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(h.maskAttr(a))
+		return true
+	})
+	return h.inner.Handle(ctx, out)
+}
+
+func (h *SecretsHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	masked := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		masked[i] = h.maskAttr(a)
+	}
+	h2 := *h
+	h2.inner = h.inner.WithAttrs(masked)
+	return &h2
+}
+
+func (h *SecretsHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.inner = h.inner.WithGroup(name)
+	return &h2
+}
+
+func (h *SecretsHandler) maskAttr(a slog.Attr) slog.Attr {
+	switch a.Value.Kind() {
+	case slog.KindString:
+		a.Value = slog.StringValue(h.mask(a.Value.String()))
+	case slog.KindLogValuer:
+		a.Value = a.Value.Resolve()
+		return h.maskAttr(a)
+	case slog.KindGroup:
+		attrs := a.Value.Group()
+		masked := make([]slog.Attr, len(attrs))
+		for i, ga := range attrs {
+			masked[i] = h.maskAttr(ga)
+		}
+		a.Value = slog.GroupValue(masked...)
+	case slog.KindAny:
+		var s string
+		switch v := a.Value.Any().(type) {
+		case error:
+			s = v.Error()
+		case fmt.Stringer:
+			s = v.String()
+		default:
+			return a
+		}
+		// values without secrets keep their type for the inner handler
+		if masked := h.mask(s); masked != s {
+			a.Value = slog.StringValue(masked)
+		}
+	}
+	return a
+}
+
+// mask replaces the secrets found in s.
+func (h *SecretsHandler) mask(s string) string {
+	if len(s) < 13 {
+		return s
+	}
+	s = h.replace(s, SecretAWSKey, reAWSKey, nil)
+	s = h.replace(s, SecretJWT, reJWT, nil)
+	s = h.replace(s, SecretCreditCard, reCreditCard, luhnValid)
+	if h.opts.MinEntropy > 0 {
+		s = h.replace(s, SecretHighEntropy, reToken, func(token string) bool {
+			return len(token) >= h.opts.MinTokenLen && token != h.opts.Mask &&
+				shannonEntropy(token) >= h.opts.MinEntropy
+		})
+	}
+	return s
+}
+
+func (h *SecretsHandler) replace(s, kind string, re *regexp.Regexp, valid func(string) bool) string {
+	return re.ReplaceAllStringFunc(s, func(m string) string {
+		if valid != nil && !valid(m) {
+			return m
+		}
+		h.counters.inc(kind)
+		return h.opts.Mask
+	})
+}
+
+func (c *secretCounters) inc(kind string) {
+	v, ok := c.m.Load(kind)
+	if !ok {
+		v, _ = c.m.LoadOrStore(kind, new(atomic.Uint64))
+	}
+	v.(*atomic.Uint64).Add(1)
+}
+
+// luhnValid reports whether the digits of s pass the Luhn checksum.
+func luhnValid(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
+
+// shannonEntropy returns the entropy of s in bits per byte.
+func shannonEntropy(s string) float64 {
+	var counts [256]int
+	for i := 0; i < len(s); i++ {
+		counts[s[i]]++
+	}
+	var e float64
+	n := float64(len(s))
+	for _, c := range counts {
+		if c > 0 {
+			p := float64(c) / n
+			e -= p * math.Log2(p)
+		}
+	}
+	return e
+}
@@ -0,0 +1,235 @@
+// Package slogaudit emits audit events: who did what to which target, and
+// with what outcome. Events are validated against a schema before they are
+// written, and can carry a hash chain making removed or altered records
+// detectable.
+package slogaudit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/fpawel/slogx/slogctx"
+)
+
+// Outcome of an audited action.
+type Outcome string
+
+const (
+	Success Outcome = "success"
+	Failure Outcome = "failure"
+	Denied  Outcome = "denied"
+)
+
+// Record attribute keys of audit events.
+const (
+	ActorKey    = "actor"
+	ActionKey   = "action"
+	TargetKey   = "target"
+	OutcomeKey  = "outcome"
+	MetadataKey = "metadata"
+	PrevHashKey = "prev_hash"
+	HashKey     = "hash"
+)
+
+type (
+	// Event is an audited action.
+	Event struct {
+		Time     time.Time // the current time if zero
+		Actor    string    // taken from the context if empty, see WithActor
+		Action   string
+		Target   string
+		Outcome  Outcome
+		Metadata map[string]any
+	}
+
+	// Schema restricts the events which can be logged.
+	Schema struct {
+		// Actions maps the allowed actions to the metadata keys they require.
+		// Any action is allowed if it is empty.
+		Actions map[string][]string
+	}
+
+	// Options configures Logger.
+	Options struct {
+		Schema    Schema
+		Level     slog.Level // slog.LevelInfo by default
+		HashChain bool       // add prev_hash and hash attributes, see Hash
+
+		// PrevHash seeds the hash chain with the hash of the last event
+		// written before, e.g. saved with Logger.PrevHash at shutdown, so
+		// the chain continues across restarts
+		PrevHash string
+	}
+
+	// Logger writes audit events to a dedicated handler chain.
+	Logger struct {
+		handler slog.Handler
+		opts    Options
+
+		mu       sync.Mutex
+		prevHash string
+	}
+)
+
+type ctxActorKey struct{}
+
+// ErrInvalidEvent is returned by Schema.Validate and Logger.Log for events not matching the schema.
+var ErrInvalidEvent = errors.New("invalid audit event")
+
+// ErrDisabled is returned by Logger.Log when the handler is not enabled for
+// the level of audit events, so a misconfigured handler does not drop them silently.
+var ErrDisabled = errors.New("audit handler disabled")
+
+// WithActor returns a copy of ctx carrying the actor of audit events.
+// The actor is also stored as a slogctx field, so the regular log records
+// made with the context identify the actor too.
+func WithActor(ctx context.Context, actor string) context.Context {
+	ctx = context.WithValue(ctx, ctxActorKey{}, actor)
+	return slogctx.WithValues(ctx, ActorKey, actor)
+}
+
+// Actor returns the actor stored in ctx by WithActor.
+func Actor(ctx context.Context) string {
+	actor, _ := ctx.Value(ctxActorKey{}).(string)
+	return actor
+}
+
+// New creates Logger writing audit events to h
+func New(h slog.Handler, opts *Options) *Logger {
+	l := &Logger{handler: h}
+	if opts != nil {
+		l.opts = *opts
+	}
+	l.prevHash = l.opts.PrevHash
+	return l
+}
+
+// PrevHash returns the hash of the last event written, to be saved and passed
+// as Options.PrevHash to continue the chain after a restart.
+func (l *Logger) PrevHash() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.prevHash
+}
+
+// Validate reports whether e has all the mandatory fields and matches the schema.
+func (s Schema) Validate(e Event) error {
+	var missing []string
+	if e.Actor == "" {
+		missing = append(missing, ActorKey)
+	}
+	if e.Action == "" {
+		missing = append(missing, ActionKey)
+	}
+	if e.Target == "" {
+		missing = append(missing, TargetKey)
+	}
+	if e.Outcome == "" {
+		missing = append(missing, OutcomeKey)
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: missing %v", ErrInvalidEvent, missing)
+	}
+	switch e.Outcome {
+	case Success, Failure, Denied:
+	default:
+		return fmt.Errorf("%w: unknown outcome %q", ErrInvalidEvent, e.Outcome)
+	}
+	if len(s.Actions) == 0 {
+		return nil
+	}
+	required, ok := s.Actions[e.Action]
+	if !ok {
+		return fmt.Errorf("%w: unknown action %q", ErrInvalidEvent, e.Action)
+	}
+	for _, k := range required {
+		if _, ok := e.Metadata[k]; !ok {
+			missing = append(missing, MetadataKey+"."+k)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: action %q requires %v", ErrInvalidEvent, e.Action, missing)
+	}
+	return nil
+}
+
+// Log validates and writes the event. Unlike regular logging, failures are
+// returned, since audit events must not be lost silently.
+func (l *Logger) Log(ctx context.Context, e Event) error {
+	if e.Actor == "" {
+		e.Actor = Actor(ctx)
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if err := l.opts.Schema.Validate(e); err != nil {
+		return err
+	}
+	if !l.handler.Enabled(ctx, l.opts.Level) {
+		return ErrDisabled
+	}
+
+	r := slog.NewRecord(e.Time, l.opts.Level, "audit "+e.Action, 0)
+	r.AddAttrs(
+		slog.String(ActorKey, e.Actor),
+		slog.String(ActionKey, e.Action),
+		slog.String(TargetKey, e.Target),
+		slog.String(OutcomeKey, string(e.Outcome)))
+	if len(e.Metadata) > 0 {
+		keys := make([]string, 0, len(e.Metadata))
+		for k := range e.Metadata {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		attrs := make([]slog.Attr, len(keys))
+		for i, k := range keys {
+			attrs[i] = slog.Any(k, e.Metadata[k])
+		}
+		r.AddAttrs(slog.Attr{Key: MetadataKey, Value: slog.GroupValue(attrs...)})
+	}
+	if !l.opts.HashChain {
+		return l.handler.Handle(ctx, r)
+	}
+
+	// the chain must follow the order of writing
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	hash, err := Hash(l.prevHash, e)
+	if err != nil {
+		return err
+	}
+	r.AddAttrs(slog.String(PrevHashKey, l.prevHash), slog.String(HashKey, hash))
+	if err := l.handler.Handle(ctx, r); err != nil {
+		return err
+	}
+	l.prevHash = hash
+	return nil
+}
+
+// Hash returns the hex SHA-256 of the previous hash and the JSON encoding of
+// the event. Verifiers recompute it for each logged event, in order, and
+// compare it with the hash attribute.
+func Hash(prevHash string, e Event) (string, error) {
+	b, err := json.Marshal(struct {
+		PrevHash string         `json:"prev_hash"`
+		Time     time.Time      `json:"time"`
+		Actor    string         `json:"actor"`
+		Action   string         `json:"action"`
+		Target   string         `json:"target"`
+		Outcome  Outcome        `json:"outcome"`
+		Metadata map[string]any `json:"metadata,omitempty"`
+	}{prevHash, e.Time.UTC(), e.Actor, e.Action, e.Target, e.Outcome, e.Metadata})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
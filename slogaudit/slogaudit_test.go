@@ -0,0 +1,100 @@
+package slogaudit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestLogger(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := New(slog.NewJSONHandler(buf, nil), &Options{
+		Schema: Schema{Actions: map[string][]string{
+			"user.delete": {"reason"},
+		}},
+		HashChain: true,
+	})
+	ctx := WithActor(context.Background(), "admin")
+
+	if err := logger.Log(ctx, Event{Action: "user.delete", Target: "user:42", Outcome: Success}); !errors.Is(err, ErrInvalidEvent) {
+		t.Fatalf("expected missing metadata error, got %v", err)
+	}
+	if err := logger.Log(ctx, Event{Action: "user.create", Target: "user:42", Outcome: Success}); !errors.Is(err, ErrInvalidEvent) {
+		t.Fatalf("expected unknown action error, got %v", err)
+	}
+
+	tm := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	events := []Event{
+		{Time: tm, Action: "user.delete", Target: "user:42", Outcome: Success, Metadata: map[string]any{"reason": "spam"}},
+		{Time: tm, Action: "user.delete", Target: "user:43", Outcome: Denied, Metadata: map[string]any{"reason": "test"}},
+	}
+	for _, e := range events {
+		if err := logger.Log(ctx, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dec := json.NewDecoder(buf)
+	prev := ""
+	for _, e := range events {
+		var m map[string]any
+		if err := dec.Decode(&m); err != nil {
+			t.Fatal(err)
+		}
+		if m[ActorKey] != "admin" || m[PrevHashKey] != prev {
+			t.Fatalf("unexpected record %v", m)
+		}
+		e.Actor = "admin"
+		hash, err := Hash(prev, e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m[HashKey] != hash {
+			t.Fatalf("hash %v, expected %s", m[HashKey], hash)
+		}
+		prev = hash
+	}
+}
+
+func TestLoggerDisabled(t *testing.T) {
+	logger := New(slog.NewJSONHandler(new(bytes.Buffer), &slog.HandlerOptions{Level: slog.LevelWarn}), nil)
+	e := Event{Actor: "admin", Action: "user.delete", Target: "user:42", Outcome: Success}
+	if err := logger.Log(context.Background(), e); !errors.Is(err, ErrDisabled) {
+		t.Fatalf("expected disabled error, got %v", err)
+	}
+}
+
+func TestLoggerPrevHash(t *testing.T) {
+	e := Event{
+		Time:    time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		Actor:   "admin",
+		Action:  "user.delete",
+		Target:  "user:42",
+		Outcome: Success,
+	}
+	first := New(slog.NewJSONHandler(new(bytes.Buffer), nil), &Options{HashChain: true})
+	if err := first.Log(context.Background(), e); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := new(bytes.Buffer)
+	second := New(slog.NewJSONHandler(buf, nil), &Options{HashChain: true, PrevHash: first.PrevHash()})
+	if err := second.Log(context.Background(), e); err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := Hash(first.PrevHash(), e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m[PrevHashKey] != first.PrevHash() || m[HashKey] != hash || second.PrevHash() != hash {
+		t.Fatalf("unexpected record %v", m)
+	}
+}
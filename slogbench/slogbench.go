@@ -0,0 +1,180 @@
+// Package slogbench measures the cost of slog handler stacks: time,
+// allocations and bytes emitted per record, under representative workloads.
+//
+// In tests, run the workloads as sub-benchmarks:
+//
+//	func BenchmarkJSON(b *testing.B) {
+//		slogbench.Run(b, func(w io.Writer) slog.Handler {
+//			return slog.NewJSONHandler(w, nil)
+//		})
+//	}
+//
+// Outside tests, compare several stacks with Measure and WriteReport.
+package slogbench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fpawel/slogx/slogctx"
+)
+
+type (
+	// NewHandler creates the handler stack under test writing to w.
+	NewHandler func(w io.Writer) slog.Handler
+
+	// Stack is a named handler stack to compare.
+	Stack struct {
+		Name string
+		New  NewHandler
+	}
+
+	// Workload is a logging call pattern.
+	Workload struct {
+		Name string
+		// Context returns the context of the logging calls
+		Context func() context.Context
+		// Log makes one logging call
+		Log func(ctx context.Context, logger *slog.Logger)
+	}
+
+	// Result is the measurement of a workload on a stack.
+	Result struct {
+		Stack       string
+		Workload    string
+		NsPerOp     float64
+		AllocsPerOp int64
+		AllocBytes  int64   // heap bytes allocated per record
+		OutputBytes float64 // bytes written per record
+	}
+)
+
+var (
+	testErr  = errors.New("connection reset by peer")
+	testTime = time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+)
+
+// Workloads are the workloads run by Run and Measure.
+var Workloads = []Workload{
+	{
+		Name: "FewAttrs",
+		Log: func(ctx context.Context, logger *slog.Logger) {
+			logger.InfoContext(ctx, "request done", "status", 200, "path", "/api/v1/users")
+		},
+	},
+	{
+		Name: "ManyAttrs",
+		Log: func(ctx context.Context, logger *slog.Logger) {
+			logger.LogAttrs(ctx, slog.LevelInfo, "request done",
+				slog.Int("status", 200),
+				slog.String("method", "GET"),
+				slog.String("path", "/api/v1/users"),
+				slog.String("remote_addr", "10.0.0.1:51234"),
+				slog.Duration("elapsed", 1234*time.Microsecond),
+				slog.Int64("bytes", 5120),
+				slog.Bool("cached", false),
+				slog.Float64("ratio", 0.75),
+				slog.Time("started", testTime),
+				slog.Any("err", testErr))
+		},
+	},
+	{
+		Name: "DeepGroups",
+		Log: func(ctx context.Context, logger *slog.Logger) {
+			logger.InfoContext(ctx, "request done",
+				slog.Group("http",
+					slog.Group("request",
+						slog.String("method", "GET"),
+						slog.Group("url", slog.String("path", "/api"), slog.String("query", "q=1"))),
+					slog.Group("response", slog.Int("status", 200))))
+		},
+	},
+	{
+		Name: "ContextFields",
+		Context: func() context.Context {
+			return slogctx.WithValues(context.Background(),
+				"request_id", "7f3c9a2e", "user_id", 42, "tenant", "acme")
+		},
+		Log: func(ctx context.Context, logger *slog.Logger) {
+			logger.InfoContext(ctx, "request done", "status", 200)
+		},
+	},
+}
+
+// Run runs the workloads on the handler stack as sub-benchmarks of b,
+// reporting the bytes written per record as the "out-B/op" metric.
+func Run(b *testing.B, newHandler NewHandler) {
+	for _, wl := range Workloads {
+		b.Run(wl.Name, func(b *testing.B) {
+			benchmark(b, newHandler, wl)
+		})
+	}
+}
+
+// Measure runs all the workloads on all the stacks.
+func Measure(stacks ...Stack) []Result {
+	var results []Result
+	for _, s := range stacks {
+		for _, wl := range Workloads {
+			var out float64
+			r := testing.Benchmark(func(b *testing.B) {
+				out = benchmark(b, s.New, wl)
+			})
+			results = append(results, Result{
+				Stack:       s.Name,
+				Workload:    wl.Name,
+				NsPerOp:     float64(r.T.Nanoseconds()) / float64(max(r.N, 1)),
+				AllocsPerOp: r.AllocsPerOp(),
+				AllocBytes:  r.AllocedBytesPerOp(),
+				OutputBytes: out,
+			})
+		}
+	}
+	return results
+}
+
+// WriteReport writes results as an aligned table.
+func WriteReport(w io.Writer, results []Result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "stack\tworkload\tns/op\tallocs/op\tB/op\tout-B/op\t")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%.0f\t%d\t%d\t%.0f\t\n",
+			r.Stack, r.Workload, r.NsPerOp, r.AllocsPerOp, r.AllocBytes, r.OutputBytes)
+	}
+	return tw.Flush()
+}
+
+// benchmark runs the workload b.N times and returns the bytes written per record.
+func benchmark(b *testing.B, newHandler NewHandler, wl Workload) float64 {
+	w := new(countingWriter)
+	logger := slog.New(newHandler(w))
+	ctx := context.Background()
+	if wl.Context != nil {
+		ctx = wl.Context()
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		wl.Log(ctx, logger)
+	}
+	b.StopTimer()
+	out := float64(w.n.Load()) / float64(b.N)
+	b.ReportMetric(out, "out-B/op")
+	return out
+}
+
+type countingWriter struct {
+	n atomic.Int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n.Add(int64(len(p)))
+	return len(p), nil
+}
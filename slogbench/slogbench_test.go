@@ -0,0 +1,28 @@
+package slogbench
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/fpawel/slogx/pretty"
+	"github.com/fpawel/slogx/slogctx"
+)
+
+func BenchmarkJSON(b *testing.B) {
+	Run(b, func(w io.Writer) slog.Handler {
+		return slog.NewJSONHandler(w, nil)
+	})
+}
+
+func BenchmarkSlogctx(b *testing.B) {
+	Run(b, func(w io.Writer) slog.Handler {
+		return slogctx.NewHandler(slog.NewJSONHandler(w, nil))
+	})
+}
+
+func BenchmarkPretty(b *testing.B) {
+	Run(b, func(w io.Writer) slog.Handler {
+		return pretty.NewHandler().WithOutput(w)
+	})
+}
@@ -0,0 +1,106 @@
+package slogctx
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+)
+
+type ctxBufferKey string
+
+const keyBuffer ctxBufferKey = "slog_buffer"
+
+type (
+	// recordBuffer keeps the records of a context until Flush
+	recordBuffer struct {
+		mu          sync.Mutex
+		entries     []bufferedRecord
+		onlyOnError bool
+		failed      bool // an Error record was buffered
+	}
+
+	bufferedRecord struct {
+		ctx     context.Context
+		handler slog.Handler
+		record  slog.Record
+	}
+
+	// BufferHandler keeps the records handled with a context of
+	// BufferedContext until Flush is called with the context, so the logs of
+	// a request are written together. Records handled with other contexts are
+	// passed to the inner handler at once.
+	//
+	//	h := slogctx.NewHandler(slogctx.NewBufferHandler(slog.NewJSONHandler(os.Stdout, nil)))
+	//
+	//	ctx := slogctx.BufferedContext(r.Context(), true)
+	//	defer slogctx.Flush(ctx)
+	BufferHandler struct {
+		inner slog.Handler
+	}
+)
+
+var _ slog.Handler = (*BufferHandler)(nil)
+
+// NewBufferHandler creates BufferHandler wrapping inner
+func NewBufferHandler(inner slog.Handler) *BufferHandler {
+	return &BufferHandler{inner: inner}
+}
+
+// BufferedContext returns a copy of ctx whose records are kept by
+// BufferHandler until Flush. If onlyOnError is true, Flush writes the records
+// only if one of them is at Error level or above, and discards them otherwise.
+func BufferedContext(ctx context.Context, onlyOnError bool) context.Context {
+	return context.WithValue(ctx, keyBuffer, &recordBuffer{onlyOnError: onlyOnError})
+}
+
+// Flush writes the records of ctx kept by BufferHandler, in the order they
+// were logged, and empties the buffer. It returns the errors of the handlers joined.
+func Flush(ctx context.Context) error {
+	b, ok := ctx.Value(keyBuffer).(*recordBuffer)
+	if !ok {
+		return nil
+	}
+	b.mu.Lock()
+	entries, failed := b.entries, b.failed
+	b.entries, b.failed = nil, false
+	b.mu.Unlock()
+
+	if b.onlyOnError && !failed {
+		return nil
+	}
+	var errs []error
+	for _, e := range entries {
+		errs = append(errs, e.handler.Handle(e.ctx, e.record))
+	}
+	return errors.Join(errs...)
+}
+
+// Unwrap returns the inner handler.
+func (h *BufferHandler) Unwrap() slog.Handler {
+	return h.inner
+}
+
+func (h *BufferHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+func (h *BufferHandler) Handle(ctx context.Context, record slog.Record) error {
+	b, ok := ctx.Value(keyBuffer).(*recordBuffer)
+	if !ok {
+		return h.inner.Handle(ctx, record)
+	}
+	b.mu.Lock()
+	b.entries = append(b.entries, bufferedRecord{ctx: ctx, handler: h.inner, record: record.Clone()})
+	b.failed = b.failed || record.Level >= slog.LevelError
+	b.mu.Unlock()
+	return nil
+}
+
+func (h *BufferHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &BufferHandler{inner: h.inner.WithAttrs(attrs)}
+}
+
+func (h *BufferHandler) WithGroup(name string) slog.Handler {
+	return &BufferHandler{inner: h.inner.WithGroup(name)}
+}
@@ -3,41 +3,268 @@ package slogctx
 import (
 	"context"
 	"log/slog"
-	"sync"
+	"slices"
+	"strings"
+	"time"
 )
 
 var _ slog.Handler = Handler{}
 
+// Dedup is the policy of Handler for the attributes of a record with the same key.
+type Dedup int
+
+const (
+	DedupNone      Dedup = iota // keep all the attributes
+	DedupLastWins               // keep the value of the last attribute with the key
+	DedupFirstWins              // keep the value of the first attribute with the key
+)
+
+// Handler adds the log fields of the context to the records of the wrapped handler.
 type Handler struct {
 	slog.Handler
+	opts    handlerOptions
+	carried *fieldsData // the fields added to the handler by FromContext
 }
 
-func NewHandler(handler slog.Handler) slog.Handler {
+type handlerOptions struct {
+	dedup      Dedup
+	prepend    bool
+	extractors []func(context.Context) []slog.Attr
+	mask       func(any) slog.Value
+	rewrite    func(key string, val any) (string, any, bool)
+	dotted     bool
+	ctxState   bool
+	defaults   []slog.Attr
+}
+
+func NewHandler(handler slog.Handler) Handler {
 	return Handler{
 		Handler: handler,
+		opts:    handlerOptions{mask: MaskValue},
 	}
 }
 
+// WithMask returns a copy of the handler replacing the values of sensitive
+// log fields with mask, MaskValue by default, e.g. HashValue.
+func (h Handler) WithMask(mask func(any) slog.Value) Handler {
+	h.opts.mask = mask
+	return h
+}
+
+// WithDedup returns a copy of the handler collapsing the top-level attributes
+// of a record with the same key, call-site and context ones, by the policy,
+// for consumers which can't tolerate repeated JSON keys. Each key keeps the
+// position of its first attribute.
+func (h Handler) WithDedup(policy Dedup) Handler {
+	h.opts.dedup = policy
+	return h
+}
+
+// Enabled reports whether the level is enabled by the minimum level of the
+// context set with WithMinLevel, if any, otherwise by the wrapped handler.
 func (h Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	if minLevel, ok := MinLevel(ctx); ok {
+		return level >= minLevel
+	}
 	return h.Handler.Enabled(ctx, level)
 }
 
+// WithPrepend returns a copy of the handler adding the log fields of the
+// context before the call-site attributes of records instead of after them,
+// so fields like request_id come first in text output, and call-site
+// attributes override them in last-wins consumers.
+func (h Handler) WithPrepend(prepend bool) Handler {
+	h.opts.prepend = prepend
+	return h
+}
+
+// WithExtractor returns a copy of the handler also adding the attributes
+// returned by the extractor for the context to records, after the log fields,
+// e.g. to log the values other libraries store in the context:
+//
+//	h = h.WithExtractor(func(ctx context.Context) []slog.Attr {
+//		if id := middleware.GetReqID(ctx); id != "" {
+//			return []slog.Attr{slog.String("request_id", id)}
+//		}
+//		return nil
+//	})
+func (h Handler) WithExtractor(extractor func(ctx context.Context) []slog.Attr) Handler {
+	h.opts.extractors = append(slices.Clip(h.opts.extractors), extractor)
+	return h
+}
+
+// WithAttrRewriter returns a copy of the handler calling rewrite for each log
+// field of the context before adding it to a record; the field is renamed to
+// the key returned, with the value returned, or dropped if ok is false,
+// e.g. to rename uid to user_id to match a logging schema. The key of a field
+// of WithGroupValues is prefixed by its group and a dot, and the field is
+// added ungrouped if renamed.
+func (h Handler) WithAttrRewriter(rewrite func(key string, val any) (newKey string, newVal any, ok bool)) Handler {
+	h.opts.rewrite = rewrite
+	return h
+}
+
+// WithDottedGroups returns a copy of the handler expanding the dotted keys of
+// the context fields to nested groups, e.g. http.request.id to
+// {"http":{"request":{"id":...}}}, for backends expecting nested objects.
+func (h Handler) WithDottedGroups(dotted bool) Handler {
+	h.opts.dotted = dotted
+	return h
+}
+
+// WithContextState returns a copy of the handler adding the ctx.deadline_in
+// attribute, the time left until the deadline, to the records handled with a
+// context having a deadline, and the ctx.cancel_cause attribute to the records
+// handled with a canceled context, to debug timeouts.
+func (h Handler) WithContextState(enabled bool) Handler {
+	h.opts.ctxState = enabled
+	return h
+}
+
+// WithDefaultAttrs returns a copy of the handler adding the attributes to the
+// records handled with a context without log fields, so e.g. background jobs
+// get a minimal identification set like the job name and worker ID.
+func (h Handler) WithDefaultAttrs(attrs ...slog.Attr) Handler {
+	h.opts.defaults = append(slices.Clip(h.opts.defaults), attrs...)
+	return h
+}
+
 func (h Handler) Handle(ctx context.Context, record slog.Record) error {
-	if v, ok := ctx.Value(keyFields).(*sync.Map); ok {
-		v.Range(func(key, val any) bool {
-			if keyString, ok := key.(string); ok {
-				record.AddAttrs(slog.Any(keyString, val))
-			}
-			return true
-		})
+	if fields := h.fields(ctx, record.Level); len(fields) > 0 {
+		if h.opts.prepend {
+			out := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+			out.AddAttrs(fields...)
+			record.Attrs(func(a slog.Attr) bool {
+				out.AddAttrs(a)
+				return true
+			})
+			record = out
+		} else {
+			record.AddAttrs(fields...)
+		}
+	}
+	if h.opts.dedup != DedupNone {
+		record = h.dedup(record)
 	}
 	return h.Handler.Handle(ctx, record)
 }
 
+// fields returns the attributes the context adds to records.
+func (h Handler) fields(ctx context.Context, level slog.Level) []slog.Attr {
+	var xs []slog.Attr
+	d, ok := ctx.Value(keyFields).(*fieldsData)
+	if !ok || d.len == 0 {
+		xs = slices.Clone(h.opts.defaults)
+	} else {
+		if h.carried != nil {
+			d = d.notCarried(h.carried)
+		}
+		if level > slog.LevelDebug {
+			d = d.withoutDebug()
+		}
+		mask := h.opts.mask
+		if mask == nil {
+			mask = MaskValue
+		}
+		if h.opts.rewrite != nil {
+			d = d.rewrite(h.opts.rewrite)
+		}
+		xs = d.attrs(mask)
+	}
+	for _, extract := range h.opts.extractors {
+		xs = append(xs, extract(ctx)...)
+	}
+	if h.opts.ctxState {
+		if attr, ok := contextState(ctx); ok {
+			xs = append(xs, attr)
+		}
+	}
+	if h.opts.dotted {
+		xs = expandDotted(xs)
+	}
+	return xs
+}
+
+// contextState returns the ctx group of the deadline and cancellation of ctx.
+func contextState(ctx context.Context) (slog.Attr, bool) {
+	var attrs []any
+	if deadline, ok := ctx.Deadline(); ok {
+		attrs = append(attrs, slog.Duration("deadline_in", time.Until(deadline)))
+	}
+	if ctx.Err() != nil {
+		attrs = append(attrs, slog.String("cancel_cause", context.Cause(ctx).Error()))
+	}
+	if len(attrs) == 0 {
+		return slog.Attr{}, false
+	}
+	return slog.Group("ctx", attrs...), true
+}
+
+// expandDotted returns the attributes with dotted keys expanded to nested
+// groups, merged with the groups of the same name at the place of the first.
+func expandDotted(attrs []slog.Attr) []slog.Attr {
+	var xs []slog.Attr
+	for _, a := range attrs {
+		xs = insertPath(xs, strings.Split(a.Key, "."), a.Value)
+	}
+	return xs
+}
+
+func insertPath(xs []slog.Attr, path []string, v slog.Value) []slog.Attr {
+	if len(path) == 1 {
+		if v.Kind() == slog.KindGroup {
+			for _, a := range v.Group() {
+				xs = insertPath(xs, append(slices.Clip(path), a.Key), a.Value)
+			}
+			if len(v.Group()) > 0 {
+				return xs
+			}
+		}
+		return append(xs, slog.Attr{Key: path[0], Value: v})
+	}
+	for i, x := range xs {
+		if x.Key == path[0] && x.Value.Kind() == slog.KindGroup {
+			xs[i].Value = slog.GroupValue(insertPath(slices.Clone(x.Value.Group()), path[1:], v)...)
+			return xs
+		}
+	}
+	return append(xs, slog.Attr{Key: path[0], Value: slog.GroupValue(insertPath(nil, path[1:], v)...)})
+}
+
+// dedup returns the record with one attribute per key.
+func (h Handler) dedup(record slog.Record) slog.Record {
+	attrs := make([]slog.Attr, 0, record.NumAttrs())
+	index := make(map[string]int, record.NumAttrs())
+	record.Attrs(func(a slog.Attr) bool {
+		i, ok := index[a.Key]
+		switch {
+		case !ok:
+			index[a.Key] = len(attrs)
+			attrs = append(attrs, a)
+		case h.opts.dedup == DedupLastWins:
+			attrs[i] = a
+		}
+		return true
+	})
+	if len(attrs) == record.NumAttrs() {
+		return record
+	}
+	out := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	out.AddAttrs(attrs...)
+	return out
+}
+
+// Unwrap returns the wrapped handler
+func (h Handler) Unwrap() slog.Handler {
+	return h.Handler
+}
+
 func (h Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	return Handler{h.Handler.WithAttrs(attrs)}
+	h.Handler = h.Handler.WithAttrs(attrs)
+	return h
 }
 
 func (h Handler) WithGroup(name string) slog.Handler {
-	return h.Handler.WithGroup(name)
+	h.Handler = h.Handler.WithGroup(name)
+	return h
 }
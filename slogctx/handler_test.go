@@ -1,11 +1,20 @@
 package slogctx
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log/slog"
+	"math"
+	"net/http"
 	"os"
+	"regexp"
+	"slices"
 	"testing"
+	"time"
 )
 
 type Struct struct {
@@ -47,3 +56,476 @@ func BenchmarkHandler(b *testing.B) {
 		logger.ErrorContext(ctx, "this is an error")
 	}
 }
+
+func TestWithGroupValues(t *testing.T) {
+	ctx := WithValues(context.Background(), "app", "api")
+	ctx = WithGroupValues(ctx, "http", "request_id", "r1")
+	ctx = WithValues(ctx, "user", 7)
+	ctx = WithGroupValues(ctx, "http", "method", "GET")
+
+	buf := new(bytes.Buffer)
+	logger := slog.New(NewHandler(slog.NewJSONHandler(buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})))
+	logger.InfoContext(ctx, "done")
+
+	expected := `{"level":"INFO","msg":"done","app":"api","http":{"request_id":"r1","method":"GET"},"user":7}` + "\n"
+	if buf.String() != expected {
+		t.Fatalf("\n%s\nexpected\n%s", buf, expected)
+	}
+}
+
+func TestGetValues(t *testing.T) {
+	ctx := WithValues(context.Background(), "id", 1, "id", 2)
+	ctx = WithGroupValues(ctx, "http", "id", 3)
+	ctx = WithAttrValues(ctx, slog.Int("id", 4))
+
+	if xs := GetValues(ctx, "id"); !slices.Equal(xs, []any{1, 2, int64(4)}) {
+		t.Fatalf("got %v", xs)
+	}
+	if xs := GetValues(ctx, "http.id"); !slices.Equal(xs, []any{3}) {
+		t.Fatalf("got %v", xs)
+	}
+	if v, ok := GetFirstValue(ctx, "id"); !ok || v != 1 {
+		t.Fatalf("got %v, %v", v, ok)
+	}
+}
+
+func TestWithMinLevel(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := slog.New(NewHandler(slog.NewTextHandler(buf, nil)))
+
+	logger.DebugContext(context.Background(), "hidden")
+	logger.DebugContext(WithMinLevel(context.Background(), slog.LevelDebug), "shown")
+	logger.InfoContext(WithMinLevel(context.Background(), slog.LevelWarn), "hidden")
+
+	if n := bytes.Count(buf.Bytes(), []byte("\n")); n != 1 || !bytes.Contains(buf.Bytes(), []byte("msg=shown")) {
+		t.Fatalf("got\n%s", buf)
+	}
+}
+
+func TestHandlerDedup(t *testing.T) {
+	ctx := WithValues(context.Background(), "id", 1, "user", "a", "id", 2)
+	for policy, expected := range map[Dedup]string{
+		DedupNone:      "msg=x id=3 id=1 user=a id=2\n",
+		DedupLastWins:  "msg=x id=2 user=a\n",
+		DedupFirstWins: "msg=x id=3 user=a\n",
+	} {
+		buf := new(bytes.Buffer)
+		logger := slog.New(NewHandler(slog.NewTextHandler(buf, &slog.HandlerOptions{
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if a.Key == slog.TimeKey || a.Key == slog.LevelKey {
+					return slog.Attr{}
+				}
+				return a
+			},
+		})).WithDedup(policy))
+		logger.InfoContext(ctx, "x", "id", 3)
+		if buf.String() != expected {
+			t.Errorf("policy %d: got %q, expected %q", policy, buf, expected)
+		}
+	}
+}
+
+func TestPropagationHTTP(t *testing.T) {
+	ctx := WithValues(context.Background(), "request_id", "r1", "secret", "s")
+	ctx = WithGroupValues(ctx, "tenant", "id", 7)
+
+	header := make(http.Header)
+	InjectHTTP(ctx, header, "request_id", "tenant.id")
+	if len(header) != 2 {
+		t.Fatalf("got %v", header)
+	}
+
+	got := ExtractHTTP(context.Background(), header, "request_id", "tenant.id", "secret")
+	expected := `[request_id=r1 tenant=[id=7]]`
+	if s := fmt.Sprint(Attrs(got)); s != expected {
+		t.Fatalf("got %s, expected %s", s, expected)
+	}
+}
+
+func TestPropagationMetadata(t *testing.T) {
+	ctx := WithValues(context.Background(), "Request_ID", "r1", "secret", "s")
+	ctx = WithGroupValues(ctx, "tenant", "id", 7)
+
+	md := ToMetadata(ctx, "Request_ID", "tenant.id")
+	expected := "map[x-log-request_id:[r1] x-log-tenant.id:[7]]"
+	if s := fmt.Sprint(md); s != expected {
+		t.Fatalf("got %s, expected %s", s, expected)
+	}
+
+	got := FromMetadata(context.Background(), md, "Request_ID", "tenant.id", "secret")
+	if s := fmt.Sprint(Attrs(got)); s != "[Request_ID=r1 tenant=[id=7]]" {
+		t.Fatalf("got %s", s)
+	}
+}
+
+func TestPropagationSensitive(t *testing.T) {
+	ctx := WithValues(context.Background(), "request_id", "r1")
+	ctx = WithSensitiveValue(ctx, "token", "t")
+
+	header := make(http.Header)
+	InjectHTTP(ctx, header, "request_id", "token")
+	if len(header) != 1 || header.Get(HeaderPrefix+"request_id") != "r1" {
+		t.Fatalf("got %v", header)
+	}
+	if md := ToMetadata(ctx, "request_id", "token"); len(md) != 1 {
+		t.Fatalf("got %v", md)
+	}
+}
+
+func TestSensitiveValue(t *testing.T) {
+	ctx := WithSensitiveValue(context.Background(), "email", "a@b.c")
+	ctx = MarkSensitive(WithValues(ctx, "token", "t"), "token")
+
+	buf := new(bytes.Buffer)
+	slog.New(NewHandler(slog.NewTextHandler(buf, nil))).InfoContext(ctx, "x")
+	if !bytes.Contains(buf.Bytes(), []byte("email=*** token=***")) {
+		t.Fatalf("got %s", buf)
+	}
+	if v, _ := GetFirstValue(ctx, "email"); v != "a@b.c" {
+		t.Fatalf("got %v", v)
+	}
+}
+
+type user struct{ id int }
+
+func (u user) LogValue() slog.Value {
+	return slog.GroupValue(slog.Int("id", u.id))
+}
+
+func TestHandlerLogValuer(t *testing.T) {
+	ctx := WithValues(context.Background(), "user", user{7})
+	if s := fmt.Sprint(Attrs(ctx)); s != "[user=[id=7]]" {
+		t.Fatalf("got %s", s)
+	}
+}
+
+func TestSetMaxFields(t *testing.T) {
+	defer SetMaxFields(0, OverflowDropOldest)
+	ctx := WithValues(context.Background(), "a", 1, "b", 2)
+
+	SetMaxFields(3, OverflowDropOldest)
+	if s := fmt.Sprint(Attrs(WithValues(ctx, "c", 3, "d", 4))); s != "[b=2 c=3 d=4]" {
+		t.Fatalf("drop oldest: got %s", s)
+	}
+	SetMaxFields(3, OverflowDropNewest)
+	if s := fmt.Sprint(Attrs(WithValues(ctx, "c", 3, "d", 4))); s != "[a=1 b=2 c=3]" {
+		t.Fatalf("drop newest: got %s", s)
+	}
+	SetMaxFields(3, OverflowError)
+	if _, err := WithValuesStrict(ctx, "c", 3, "d", 4); !errors.Is(err, ErrTooManyFields) {
+		t.Fatalf("error: got %v", err)
+	}
+}
+
+func BenchmarkWithValues(b *testing.B) {
+	b.ReportAllocs()
+	ctx := context.Background()
+	for i := 0; i < 50; i++ {
+		ctx = WithValues(ctx, fmt.Sprint("key", i), i)
+	}
+	for i := 0; i < b.N; i++ {
+		_ = WithValues(ctx, "request_id", "r1")
+	}
+}
+
+func TestHandlerDottedGroups(t *testing.T) {
+	ctx := WithValues(context.Background(), "http.request.id", "r1", "user", 7)
+	ctx = WithGroupValues(ctx, "http", "method", "GET")
+	ctx = WithValues(ctx, "http.request.size", 10)
+
+	buf := new(bytes.Buffer)
+	slog.New(NewHandler(slog.NewJSONHandler(buf, nil)).WithDottedGroups(true)).InfoContext(ctx, "x")
+	expected := `"http":{"request":{"id":"r1","size":10},"method":"GET"},"user":7}`
+	if !bytes.HasSuffix(bytes.TrimSpace(buf.Bytes()), []byte(expected)) {
+		t.Fatalf("got %s", buf)
+	}
+}
+
+func TestBufferHandler(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := slog.New(NewHandler(NewBufferHandler(slog.NewTextHandler(buf, nil))))
+
+	ctx := BufferedContext(context.Background(), true)
+	logger.InfoContext(ctx, "a")
+	if err := Flush(ctx); err != nil || buf.Len() != 0 {
+		t.Fatalf("flushed without error: %v %s", err, buf)
+	}
+
+	logger.InfoContext(ctx, "b")
+	logger.ErrorContext(ctx, "c")
+	if buf.Len() != 0 {
+		t.Fatalf("written before flush: %s", buf)
+	}
+	if err := Flush(ctx); err != nil || bytes.Count(buf.Bytes(), []byte("\n")) != 2 {
+		t.Fatalf("got %v %s", err, buf)
+	}
+}
+
+func TestHandlerAttrRewriter(t *testing.T) {
+	ctx := WithValues(context.Background(), "uid", 7, "token", "t")
+	ctx = WithGroupValues(ctx, "http", "request_id", "abc", "method", "GET")
+
+	buf := new(bytes.Buffer)
+	slog.New(NewHandler(slog.NewJSONHandler(buf, nil)).WithAttrRewriter(func(key string, val any) (string, any, bool) {
+		switch key {
+		case "uid":
+			return "user_id", val, true
+		case "token":
+			return "", nil, false
+		case "http.method":
+			return "method", val, true
+		}
+		return key, val, true
+	})).InfoContext(ctx, "x")
+	expected := `"user_id":7,"http":{"request_id":"abc"},"method":"GET"}`
+	if !bytes.HasSuffix(bytes.TrimSpace(buf.Bytes()), []byte(expected)) {
+		t.Fatalf("got %s", buf)
+	}
+}
+
+func TestDebugValues(t *testing.T) {
+	ctx := WithValues(context.Background(), "id", 1)
+	ctx = WithDebugValues(ctx, "body", "b")
+
+	if s := fmt.Sprint(Attrs(ctx)); s != "[id=1]" {
+		t.Fatalf("Attrs: got %s", s)
+	}
+	if s := fmt.Sprint(LevelAttrs(ctx, slog.LevelDebug)); s != "[id=1 body=b]" {
+		t.Fatalf("LevelAttrs: got %s", s)
+	}
+
+	buf := new(bytes.Buffer)
+	ctx = NewContext(ctx, slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})))
+	FromContext(ctx).Info("x")
+	expected := "level=INFO msg=x id=1\n"
+	if buf.String() != expected {
+		t.Fatalf("\n%s\nexpected\n%s", buf, expected)
+	}
+}
+
+func TestHandlerWithGroup(t *testing.T) {
+	ctx := WithValues(context.Background(), "request_id", "r1")
+
+	buf := new(bytes.Buffer)
+	logger := slog.New(NewHandler(slog.NewJSONHandler(buf, nil)).WithPrepend(true))
+	logger.WithGroup("req").With("n", 1).InfoContext(ctx, "x", "size", 2)
+	expected := `"msg":"x","req":{"n":1,"request_id":"r1","size":2}}`
+	if !bytes.HasSuffix(bytes.TrimSpace(buf.Bytes()), []byte(expected)) {
+		t.Fatalf("got %s", buf)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	tm := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	ctx := WithValues(context.Background(), "request_id", "r1")
+	ctx = WithGroupValues(ctx, "http", "method", "GET")
+	ctx = WithAttrValues(ctx,
+		slog.Group("job", slog.Int("id", 7), slog.Duration("timeout", time.Second), slog.Group("owner", slog.String("name", "a"))),
+		slog.Time("started", tm))
+	ctx = WithDebugValues(ctx, "body", "b")
+	expected := fmt.Sprint(LevelAttrs(ctx, slog.LevelDebug))
+	ctx = WithSensitiveValue(ctx, "token", "t")
+
+	b, err := json.Marshal(Snapshot(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields Fields
+	if err := json.Unmarshal(b, &fields); err != nil {
+		t.Fatal(err)
+	}
+	for _, restored := range []context.Context{
+		WithSnapshot(context.Background(), Snapshot(ctx)),
+		WithSnapshot(context.Background(), fields),
+	} {
+		if s := fmt.Sprint(LevelAttrs(restored, slog.LevelDebug)); s != expected {
+			t.Fatalf("\n%s\nexpected\n%s", s, expected)
+		}
+		if v, ok := GetFirstValue(restored, "token"); ok {
+			t.Fatalf("sensitive token %v restored", v)
+		}
+		if v, _ := GetFirstValue(restored, "started"); v != tm {
+			t.Fatalf("got started %v", v)
+		}
+	}
+}
+
+func TestSnapshotIntegers(t *testing.T) {
+	ctx := WithAttrValues(context.Background(),
+		slog.Int64("int", 9007199254740993),
+		slog.Int64("min", math.MinInt64),
+		slog.Uint64("uint", math.MaxUint64),
+		slog.Duration("ttl", math.MaxInt64))
+
+	b, err := json.Marshal(Snapshot(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields Fields
+	if err := json.Unmarshal(b, &fields); err != nil {
+		t.Fatal(err)
+	}
+	restored := WithSnapshot(context.Background(), fields)
+	if s, expected := fmt.Sprint(LevelAttrs(restored, slog.LevelInfo)), fmt.Sprint(LevelAttrs(ctx, slog.LevelInfo)); s != expected {
+		t.Fatalf("\n%s\nexpected\n%s", s, expected)
+	}
+	if v, _ := GetFirstValue(restored, "uint"); v != uint64(math.MaxUint64) {
+		t.Fatalf("got uint %v", v)
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	buf := new(bytes.Buffer)
+	h := NewHandler(slog.NewTextHandler(buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	ctx := NewContext(context.Background(), slog.New(h))
+	ctx = WithValues(ctx, "request_id", "r1")
+	ctx = WithDebugValues(ctx, "body", "b")
+
+	logger := FromContext(ctx)
+	logger.Info("plain")
+	logger.InfoContext(ctx, "same")
+	logger.DebugContext(ctx, "debug")
+	logger.InfoContext(WithValues(ctx, "user", 7), "derived")
+	logger.InfoContext(WithValues(context.Background(), "other", 1), "other")
+
+	expected := `level=INFO msg=plain request_id=r1
+level=INFO msg=same request_id=r1
+level=DEBUG msg=debug request_id=r1 body=b
+level=INFO msg=derived request_id=r1 user=7
+level=INFO msg=other request_id=r1 other=1
+`
+	if buf.String() != expected {
+		t.Fatalf("\n%s\nexpected\n%s", buf, expected)
+	}
+}
+
+func TestHandlerPrepend(t *testing.T) {
+	ctx := WithValues(context.Background(), "request_id", "r1", "id", 1)
+	for prepend, expected := range map[bool]string{
+		false: "msg=x app=api id=2 request_id=r1 id=1\n",
+		true:  "msg=x app=api request_id=r1 id=1 id=2\n",
+	} {
+		buf := new(bytes.Buffer)
+		slog.New(NewHandler(textHandler(buf)).WithPrepend(prepend)).With("app", "api").InfoContext(ctx, "x", "id", 2)
+		if buf.String() != expected {
+			t.Errorf("prepend %v:\n%s\nexpected\n%s", prepend, buf, expected)
+		}
+	}
+
+	// call-site attributes override the context ones in last-wins consumers
+	buf := new(bytes.Buffer)
+	slog.New(NewHandler(textHandler(buf)).WithPrepend(true).WithDedup(DedupLastWins)).InfoContext(ctx, "x", "id", 2)
+	if expected := "msg=x request_id=r1 id=2\n"; buf.String() != expected {
+		t.Fatalf("\n%s\nexpected\n%s", buf, expected)
+	}
+}
+
+type chiRequestIDKey struct{}
+
+func TestHandlerExtractor(t *testing.T) {
+	buf := new(bytes.Buffer)
+	h := NewHandler(textHandler(buf)).
+		WithExtractor(func(ctx context.Context) []slog.Attr {
+			if id, ok := ctx.Value(chiRequestIDKey{}).(string); ok {
+				return []slog.Attr{slog.String("request_id", id)}
+			}
+			return nil
+		}).
+		WithExtractor(func(context.Context) []slog.Attr {
+			return []slog.Attr{slog.String("region", "eu")}
+		})
+	logger := slog.New(h)
+
+	ctx := context.WithValue(context.Background(), chiRequestIDKey{}, "r1")
+	logger.InfoContext(WithValues(ctx, "user", 7), "x")
+	logger.InfoContext(context.Background(), "y")
+
+	expected := "msg=x user=7 request_id=r1 region=eu\nmsg=y region=eu\n"
+	if buf.String() != expected {
+		t.Fatalf("\n%s\nexpected\n%s", buf, expected)
+	}
+}
+
+func TestHandlerFieldsAllocs(t *testing.T) {
+	ctx := WithValues(context.Background(), "number", 12, "string", "data")
+	ctx = WithValues(ctx, "struct", Struct{Number: 42, String: "struct_data"})
+	h := NewHandler(slog.NewJSONHandler(io.Discard, nil))
+
+	// the attributes are converted once, when the fields are added
+	if n := testing.AllocsPerRun(100, func() { h.fields(ctx, slog.LevelInfo) }); n > 1 {
+		t.Fatalf("%v allocations per record, expected 1", n)
+	}
+	if fields := fieldsFrom(ctx).all(); fields[2].attr.Value.Kind() != slog.KindAny || fields[0].attr.Value.Int64() != 12 {
+		t.Fatalf("unexpected attributes %v", fields)
+	}
+}
+
+func TestHandlerContextState(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := slog.New(NewHandler(textHandler(buf)).WithContextState(true))
+
+	logger.InfoContext(context.Background(), "x")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	logger.InfoContext(ctx, "y")
+	ctx, cancel2 := context.WithCancelCause(ctx)
+	cancel2(errors.New("client gone"))
+	logger.InfoContext(ctx, "z")
+
+	expected := regexp.MustCompile(`^msg=x
+msg=y ctx.deadline_in=59m59\.\d+s
+msg=z ctx.deadline_in=59m59\.\d+s ctx.cancel_cause="client gone"
+$`)
+	if !expected.MatchString(buf.String()) {
+		t.Fatalf("got\n%s", buf)
+	}
+
+	buf.Reset()
+	slog.New(NewHandler(textHandler(buf))).InfoContext(ctx, "x")
+	if buf.String() != "msg=x\n" {
+		t.Fatalf("got %s", buf)
+	}
+}
+
+func TestHandlerDefaultAttrs(t *testing.T) {
+	buf := new(bytes.Buffer)
+	h := NewHandler(textHandler(buf)).WithDefaultAttrs(slog.String("job", "cleanup"))
+	logger := slog.New(h.WithDefaultAttrs(slog.Int("worker", 3)))
+
+	logger.InfoContext(context.Background(), "x")
+	logger.InfoContext(WithValues(context.Background(), "request_id", "r1"), "y")
+	logger.InfoContext(WithoutKeysMatching(WithValues(context.Background(), "a", 1), func(string) bool { return true }), "z")
+	slog.New(h).InfoContext(context.Background(), "w")
+
+	expected := `msg=x job=cleanup worker=3
+msg=y request_id=r1
+msg=z job=cleanup worker=3
+msg=w job=cleanup
+`
+	if buf.String() != expected {
+		t.Fatalf("\n%s\nexpected\n%s", buf, expected)
+	}
+}
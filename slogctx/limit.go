@@ -0,0 +1,64 @@
+package slogctx
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync/atomic"
+)
+
+// Overflow is the policy applied when adding log fields to a context would
+// exceed the maximum set by SetMaxFields.
+type Overflow int
+
+const (
+	OverflowDropOldest Overflow = iota // drop the oldest fields of the context
+	OverflowDropNewest                 // drop the added fields exceeding the maximum
+	OverflowError                      // add none of the fields; WithValuesStrict returns ErrTooManyFields
+)
+
+// ErrTooManyFields is returned by WithValuesStrict when the maximum number of
+// log fields is reached with the OverflowError policy.
+var ErrTooManyFields = errors.New("slogctx: too many log fields")
+
+type fieldsLimit struct {
+	max    int
+	policy Overflow
+}
+
+var limit atomic.Pointer[fieldsLimit]
+
+// SetMaxFields sets the maximum number of log fields of a context, a guard
+// against call chains accumulating hundreds of fields, and the policy applied
+// when adding more. A non-positive maximum removes the limit, which is the default.
+func SetMaxFields(max int, policy Overflow) {
+	if max <= 0 {
+		limit.Store(nil)
+		return
+	}
+	limit.Store(&fieldsLimit{max: max, policy: policy})
+}
+
+// addFields returns a copy of ctx with the fields added to its log fields,
+// within the maximum set by SetMaxFields.
+func addFields(ctx context.Context, added []field) (context.Context, error) {
+	if ctx == nil {
+		panic("cannot create context from nil parent")
+	}
+	d := fieldsFrom(ctx)
+	if l := limit.Load(); l != nil && d.len+len(added) > l.max {
+		switch l.policy {
+		case OverflowError:
+			return ctx, ErrTooManyFields
+		case OverflowDropNewest:
+			added = added[:max(l.max-d.len, 0)]
+		default:
+			fields := d.all()
+			fields = fields[min(len(fields)+len(added)-l.max, len(fields)):]
+			added = added[max(len(added)-l.max, 0):]
+			fields = append(slices.Clip(fields), added...)
+			return context.WithValue(ctx, keyFields, newFieldsData(fields)), nil
+		}
+	}
+	return context.WithValue(ctx, keyFields, d.with(added)), nil
+}
@@ -0,0 +1,79 @@
+package slogctx
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"time"
+)
+
+// Logger is a *slog.Logger whose methods take the context first, so the log
+// fields of the context can't be lost by calling Info instead of InfoContext.
+//
+//	logger := slogctx.NewLogger(slog.Default())
+//	logger.Info(ctx, "request done", "status", 200)
+type Logger struct {
+	l *slog.Logger
+}
+
+// NewLogger creates Logger logging with l, its handler wrapped by Handler unless it is one already
+func NewLogger(l *slog.Logger) Logger {
+	if _, ok := l.Handler().(Handler); !ok {
+		l = slog.New(NewHandler(l.Handler()))
+	}
+	return Logger{l: l}
+}
+
+// Slog returns the underlying *slog.Logger.
+func (l Logger) Slog() *slog.Logger {
+	return l.l
+}
+
+// With returns a Logger with the attributes added to each record, see slog.Logger.With.
+func (l Logger) With(args ...any) Logger {
+	return Logger{l: l.l.With(args...)}
+}
+
+// WithGroup returns a Logger nesting the attributes in the group, see slog.Logger.WithGroup.
+func (l Logger) WithGroup(name string) Logger {
+	return Logger{l: l.l.WithGroup(name)}
+}
+
+func (l Logger) Enabled(ctx context.Context, level slog.Level) bool {
+	return l.l.Enabled(ctx, level)
+}
+
+func (l Logger) Debug(ctx context.Context, msg string, args ...any) {
+	l.log(ctx, slog.LevelDebug, msg, args...)
+}
+
+func (l Logger) Info(ctx context.Context, msg string, args ...any) {
+	l.log(ctx, slog.LevelInfo, msg, args...)
+}
+
+func (l Logger) Warn(ctx context.Context, msg string, args ...any) {
+	l.log(ctx, slog.LevelWarn, msg, args...)
+}
+
+func (l Logger) Error(ctx context.Context, msg string, args ...any) {
+	l.log(ctx, slog.LevelError, msg, args...)
+}
+
+func (l Logger) Log(ctx context.Context, level slog.Level, msg string, args ...any) {
+	l.log(ctx, level, msg, args...)
+}
+
+// log logs with the source of the caller of the Logger method.
+func (l Logger) log(ctx context.Context, level slog.Level, msg string, args ...any) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if !l.l.Enabled(ctx, level) {
+		return
+	}
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:]) // skip runtime.Callers, log and the Logger method
+	r := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	r.Add(args...)
+	_ = l.l.Handler().Handle(ctx, r)
+}
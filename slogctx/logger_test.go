@@ -0,0 +1,63 @@
+package slogctx
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"path/filepath"
+	"testing"
+)
+
+func TestLogger(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := NewLogger(slog.New(textHandler(buf)))
+	if _, ok := logger.Slog().Handler().(Handler); !ok {
+		t.Fatalf("handler %T not wrapped", logger.Slog().Handler())
+	}
+	if NewLogger(logger.Slog()).Slog() != logger.Slog() {
+		t.Fatal("Handler wrapped twice")
+	}
+
+	ctx := WithValues(context.Background(), "request_id", "r1")
+	logger.Debug(ctx, "debug")
+	logger.Info(ctx, "info", "n", 1)
+	logger.Warn(ctx, "warn")
+	logger.Error(ctx, "error")
+	logger.Log(ctx, slog.LevelWarn+1, "log")
+	logger.With("app", "api").WithGroup("g").Info(ctx, "group", "n", 2)
+	logger.Info(nil, "nil")
+
+	expected := `msg=info n=1 request_id=r1
+msg=warn request_id=r1
+msg=error request_id=r1
+msg=log request_id=r1
+msg=group app=api g.n=2 g.request_id=r1
+msg=nil
+`
+	if buf.String() != expected {
+		t.Fatalf("\n%s\nexpected\n%s", buf, expected)
+	}
+	if logger.Enabled(ctx, slog.LevelDebug) {
+		t.Fatal("debug enabled")
+	}
+}
+
+func TestLoggerSource(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := NewLogger(slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{
+		AddSource: true,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			switch a.Key {
+			case slog.SourceKey:
+				return slog.String(a.Key, filepath.Base(a.Value.Any().(*slog.Source).File))
+			case slog.MessageKey:
+				return a
+			}
+			return slog.Attr{}
+		},
+	})))
+	logger.Info(context.Background(), "x")
+	if expected := "source=logger_test.go msg=x\n"; buf.String() != expected {
+		t.Fatalf("\n%s\nexpected\n%s", buf, expected)
+	}
+}
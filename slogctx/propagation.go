@@ -0,0 +1,111 @@
+package slogctx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// HeaderPrefix prefixes the names of the HTTP headers carrying log fields.
+const HeaderPrefix = "X-Log-"
+
+// InjectHTTP sets the headers of the log fields of ctx with the allowed keys,
+// so the receiving service restores them with ExtractHTTP. The values are sent
+// as strings with line breaks replaced by spaces; the last value is sent for a
+// key added several times. Sensitive fields are not sent, as they would be
+// sent in cleartext and restored as ordinary fields.
+//
+//	slogctx.InjectHTTP(ctx, req.Header, "request_id", "tenant")
+func InjectHTTP(ctx context.Context, header http.Header, keys ...string) {
+	for key, value := range propagated(ctx, keys) {
+		header.Set(HeaderPrefix+key, strings.NewReplacer("\r", " ", "\n", " ").Replace(value))
+	}
+}
+
+// ExtractHTTP returns a copy of ctx with the log fields of the headers set by
+// InjectHTTP with the allowed keys; other headers are ignored.
+//
+//	ctx := slogctx.ExtractHTTP(r.Context(), r.Header, "request_id", "tenant")
+func ExtractHTTP(ctx context.Context, header http.Header, keys ...string) context.Context {
+	return restore(ctx, keys, func(key string) (string, bool) {
+		values := header.Values(HeaderPrefix + key)
+		if len(values) == 0 {
+			return "", false
+		}
+		return values[0], true
+	})
+}
+
+// propagated returns the string values of the log fields of ctx with the
+// keys, except the sensitive ones.
+func propagated(ctx context.Context, keys []string) map[string]string {
+	m := make(map[string]string)
+	for _, f := range fieldsFrom(ctx).all() {
+		if f.sensitive {
+			continue
+		}
+		name := f.name()
+		for _, key := range keys {
+			if name == key {
+				m[key] = fmt.Sprint(f.any())
+			}
+		}
+	}
+	return m
+}
+
+// restore returns a copy of ctx with the log fields found by lookup for the keys.
+// Dotted keys restore fields of the group before the first dot.
+func restore(ctx context.Context, keys []string, lookup func(key string) (string, bool)) context.Context {
+	for _, key := range keys {
+		value, ok := lookup(key)
+		if !ok {
+			continue
+		}
+		if group, k, dotted := strings.Cut(key, "."); dotted {
+			ctx = WithGroupValues(ctx, group, k, value)
+		} else {
+			ctx = WithValues(ctx, key, value)
+		}
+	}
+	return ctx
+}
+
+// MetadataPrefix prefixes the gRPC metadata keys carrying log fields.
+const MetadataPrefix = "x-log-"
+
+// ToMetadata returns the gRPC metadata of the log fields of ctx with the
+// allowed keys except the sensitive ones, as InjectHTTP does for headers. The result converts to
+// metadata.MD, so client and server interceptors are a few lines:
+//
+//	func unaryClient(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+//		for k, v := range slogctx.ToMetadata(ctx, keys...) {
+//			ctx = metadata.AppendToOutgoingContext(ctx, k, v[0])
+//		}
+//		return invoker(ctx, method, req, reply, cc, opts...)
+//	}
+//
+//	func unaryServer(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+//		md, _ := metadata.FromIncomingContext(ctx)
+//		return handler(slogctx.FromMetadata(ctx, md, keys...), req)
+//	}
+func ToMetadata(ctx context.Context, keys ...string) map[string][]string {
+	md := make(map[string][]string)
+	for key, value := range propagated(ctx, keys) {
+		md[MetadataPrefix+strings.ToLower(key)] = []string{value}
+	}
+	return md
+}
+
+// FromMetadata returns a copy of ctx with the log fields of the gRPC metadata
+// set by ToMetadata with the allowed keys; other metadata is ignored.
+func FromMetadata(ctx context.Context, md map[string][]string, keys ...string) context.Context {
+	return restore(ctx, keys, func(key string) (string, bool) {
+		values := md[MetadataPrefix+strings.ToLower(key)]
+		if len(values) == 0 {
+			return "", false
+		}
+		return values[0], true
+	})
+}
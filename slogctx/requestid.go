@@ -0,0 +1,52 @@
+package slogctx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// RequestIDKey is the key of the log field of WithRequestID.
+var RequestIDKey = "request_id"
+
+// WithRequestID returns a copy of ctx with a new random UUID (version 4) as
+// the request ID log field, and the ID. If ctx has a request ID already, ctx
+// and the ID are returned.
+//
+//	ctx, id := slogctx.WithRequestID(r.Context())
+//	w.Header().Set("X-Request-ID", id)
+func WithRequestID(ctx context.Context) (context.Context, string) {
+	if id := RequestID(ctx); id != "" {
+		return ctx, id
+	}
+	id := newUUID()
+	return WithString(ctx, RequestIDKey, id), id
+}
+
+// RequestID returns the request ID log field of ctx, or an empty string.
+func RequestID(ctx context.Context) string {
+	values := GetValues(ctx, RequestIDKey)
+	if len(values) == 0 {
+		return ""
+	}
+	id, _ := values[len(values)-1].(string)
+	return id
+}
+
+func newUUID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // variant 10
+	var s [36]byte
+	hex.Encode(s[0:8], b[0:4])
+	s[8] = '-'
+	hex.Encode(s[9:13], b[4:6])
+	s[13] = '-'
+	hex.Encode(s[14:18], b[6:8])
+	s[18] = '-'
+	hex.Encode(s[19:23], b[8:10])
+	s[23] = '-'
+	hex.Encode(s[24:], b[10:])
+	return string(s[:])
+}
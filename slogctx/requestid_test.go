@@ -0,0 +1,36 @@
+package slogctx
+
+import (
+	"context"
+	"regexp"
+	"testing"
+)
+
+func TestWithRequestID(t *testing.T) {
+	if id := RequestID(context.Background()); id != "" {
+		t.Fatalf("got %q without request ID", id)
+	}
+	ctx, id := WithRequestID(context.Background())
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(id) {
+		t.Fatalf("invalid UUID %q", id)
+	}
+	if RequestID(ctx) != id {
+		t.Fatalf("got %q, expected %q", RequestID(ctx), id)
+	}
+	if ctx2, id2 := WithRequestID(ctx); ctx2 != ctx || id2 != id {
+		t.Fatal("request ID replaced")
+	}
+	if _, id2 := WithRequestID(context.Background()); id2 == id {
+		t.Fatal("request ID reused")
+	}
+}
+
+func TestRequestIDKey(t *testing.T) {
+	defer func(key string) { RequestIDKey = key }(RequestIDKey)
+	RequestIDKey = "trace_id"
+
+	ctx := WithValues(context.Background(), "trace_id", "t1")
+	if ctx2, id := WithRequestID(ctx); ctx2 != ctx || id != "t1" {
+		t.Fatalf("got %q", id)
+	}
+}
@@ -2,41 +2,522 @@ package slogctx
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"log/slog"
-	"sync"
+	"regexp"
+	"slices"
+	"strings"
+	"sync/atomic"
 )
 
 type (
-	ctxFieldsKey string
-	ctxLoggerKey string
+	ctxFieldsKey   string
+	ctxLoggerKey   string
+	ctxMinLevelKey string
 )
 
 const (
-	keyFields ctxFieldsKey = "slog_fields"
-	keyLogger ctxLoggerKey = "slog_logger"
+	keyFields   ctxFieldsKey   = "slog_fields"
+	keyLogger   ctxLoggerKey   = "slog_logger"
+	keyMinLevel ctxMinLevelKey = "slog_min_level"
+)
+
+// ErrInvalidValues is returned by WithValuesStrict for malformed arguments.
+var ErrInvalidValues = errors.New("slogctx: invalid values")
+
+type (
+	// field is a log attribute stored in a context, nested in the group if not empty.
+	// The attribute is built when the field is added, so records get it as is.
+	field struct {
+		group     string
+		attr      slog.Attr
+		value     any  // as passed, returned by GetValues
+		sensitive bool // masked in records
+		debug     bool // added to Debug records only
+	}
+
+	// fieldsData is the immutable list of the fields of a context: the fields
+	// added to the context and the list of the parent context, so adding
+	// fields is O(added) and shares the fields of the parents.
+	fieldsData struct {
+		parent *fieldsData
+		added  []field
+		len    int // of all the fields
+
+		flat atomic.Pointer[[]field] // all the fields, computed on first use
+	}
 )
 
 func WithLog(ctx context.Context, logger *slog.Logger) context.Context {
 	return context.WithValue(ctx, keyLogger, logger)
 }
 
+// Log returns the logger of ctx stored with WithLog or NewContext, or slog.Default
 func Log(ctx context.Context) *slog.Logger {
-	if v, ok := ctx.Value(keyFields).(*slog.Logger); ok {
+	if v, ok := ctx.Value(keyLogger).(*slog.Logger); ok {
 		return v
 	}
 	return slog.Default()
 }
 
-func WithValues(ctx context.Context, args ...interface{}) context.Context {
-	if ctx == nil {
-		panic("cannot create context from nil parent")
+// NewContext returns a copy of ctx carrying the logger, e.g. configured by
+// HTTP or gRPC middleware for the request.
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return WithLog(ctx, logger)
+}
+
+// FromContext returns the logger of ctx, or slog.Default, with the log fields
+// of ctx added, so they are logged by the plain methods like Info too. The
+// fields of WithDebugValues are not added, as they are for Debug records only.
+// When the handler of the logger is Handler, it skips these fields for the
+// records logged with ctx, or a context derived from it, by the *Context
+// methods, so they are not logged twice.
+func FromContext(ctx context.Context) *slog.Logger {
+	logger := Log(ctx)
+	attrs := Attrs(ctx)
+	if len(attrs) == 0 {
+		return logger
+	}
+	h := logger.Handler()
+	if x, ok := h.(Handler); ok {
+		x.carried = fieldsFrom(ctx)
+		h = x
 	}
-	v, ok := ctx.Value(keyFields).(*sync.Map)
-	if !ok {
-		v = new(sync.Map)
+	return slog.New(h.WithAttrs(attrs))
+}
+
+// WithMinLevel returns a copy of ctx with the minimum level of the records
+// logged by Handler with the context, instead of the level of the wrapped
+// handler, e.g. to log a request flagged by a debug header at Debug level.
+func WithMinLevel(ctx context.Context, level slog.Level) context.Context {
+	return context.WithValue(ctx, keyMinLevel, level)
+}
+
+// MinLevel returns the minimum level set by WithMinLevel.
+func MinLevel(ctx context.Context) (slog.Level, bool) {
+	level, ok := ctx.Value(keyMinLevel).(slog.Level)
+	return level, ok
+}
+
+// WithValues returns a copy of ctx with the key-value pairs added to the log fields of the context.
+// Pairs with a non-string key and a trailing key without value are ignored.
+func WithValues(ctx context.Context, args ...interface{}) context.Context {
+	return withFields(ctx, "", args)
+}
+
+// WithValuesStrict is like WithValues, but returns ctx unchanged and an error
+// wrapping ErrInvalidValues if the arguments are not key-value pairs with
+// non-empty string keys.
+func WithValuesStrict(ctx context.Context, args ...any) (context.Context, error) {
+	if len(args)%2 != 0 {
+		return ctx, fmt.Errorf("%w: key %v without value", ErrInvalidValues, args[len(args)-1])
 	}
 	for i := 0; i < len(args); i += 2 {
-		v.Store(args[i], args[i+1])
+		key, ok := args[i].(string)
+		if !ok {
+			return ctx, fmt.Errorf("%w: argument %d: key of type %T, expected string", ErrInvalidValues, i, args[i])
+		}
+		if key == "" {
+			return ctx, fmt.Errorf("%w: argument %d: empty key", ErrInvalidValues, i)
+		}
+	}
+	return addFields(ctx, pairs("", args))
+}
+
+// WithSensitiveValue returns a copy of ctx with the log field, which Handler
+// masks in records while GetFirstValue and GetValues return the value,
+// e.g. for personal data used in process but not to be logged.
+func WithSensitiveValue(ctx context.Context, key string, val any) context.Context {
+	ctx, _ = addFields(ctx, []field{{attr: slog.Any(key, val), value: val, sensitive: true}})
+	return ctx
+}
+
+// MarkSensitive returns a copy of ctx with the log fields of the keys marked
+// sensitive, as if added by WithSensitiveValue.
+func MarkSensitive(ctx context.Context, keys ...string) context.Context {
+	fields := slices.Clone(fieldsFrom(ctx).all())
+	for i, f := range fields {
+		if slices.Contains(keys, f.name()) {
+			fields[i].sensitive = true
+		}
+	}
+	return context.WithValue(ctx, keyFields, newFieldsData(fields))
+}
+
+// WithoutKeysMatching returns a copy of ctx without the log fields whose keys
+// match, e.g. to strip the internal fields before the context crosses a
+// trust boundary. The key of a field of WithGroupValues is prefixed by its
+// group and a dot.
+func WithoutKeysMatching(ctx context.Context, match func(key string) bool) context.Context {
+	d := fieldsFrom(ctx)
+	if !slices.ContainsFunc(d.all(), func(f field) bool { return match(f.name()) }) {
+		return ctx
+	}
+	fields := slices.DeleteFunc(slices.Clone(d.all()), func(f field) bool { return match(f.name()) })
+	return context.WithValue(ctx, keyFields, newFieldsData(fields))
+}
+
+// WithoutKeysMatchingRegexp is WithoutKeysMatching with the keys matched by re.
+//
+//	ctx = slogctx.WithoutKeysMatchingRegexp(ctx, regexp.MustCompile(`^(tmp|debug)_`))
+func WithoutKeysMatchingRegexp(ctx context.Context, re *regexp.Regexp) context.Context {
+	return WithoutKeysMatching(ctx, re.MatchString)
+}
+
+// MaskValue is the default mask of sensitive log fields.
+func MaskValue(any) slog.Value {
+	return slog.StringValue("***")
+}
+
+// HashValue is the mask of sensitive log fields keeping them correlatable:
+// the first 8 bytes of the SHA-256 of the value formatted by fmt, in hex.
+func HashValue(v any) slog.Value {
+	sum := sha256.Sum256([]byte(fmt.Sprint(v)))
+	return slog.StringValue("sha256:" + hex.EncodeToString(sum[:8]))
+}
+
+// WithDebugValues is like WithValues, but Handler adds the fields to Debug
+// records only, keeping the other records small while debug logs get the
+// full context.
+func WithDebugValues(ctx context.Context, args ...any) context.Context {
+	fields := pairs("", args)
+	for i := range fields {
+		fields[i].debug = true
+	}
+	ctx, _ = addFields(ctx, fields)
+	return ctx
+}
+
+// WithGroupValues is like WithValues, but the pairs are nested in the group
+// when the fields are added to a record, e.g. http.request_id:
+//
+//	ctx = slogctx.WithGroupValues(ctx, "http", "request_id", id, "method", r.Method)
+//
+// The fields of the same group are emitted together in one group attribute.
+func WithGroupValues(ctx context.Context, group string, args ...any) context.Context {
+	return withFields(ctx, group, args)
+}
+
+// WithAttrValues returns a copy of ctx with the attributes added to the log
+// fields of the context. Handler adds them to records unchanged, including
+// groups and slog.LogValuer values.
+func WithAttrValues(ctx context.Context, attrs ...slog.Attr) context.Context {
+	fields := make([]field, len(attrs))
+	for i, a := range attrs {
+		fields[i] = field{attr: a, value: a.Value.Any()}
+	}
+	ctx, _ = addFields(ctx, fields)
+	return ctx
+}
+
+// WithValueMap returns a copy of ctx with the entries of m added to the log
+// fields of the context in the order of the sorted keys.
+func WithValueMap(ctx context.Context, m map[string]any) context.Context {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	fields := make([]field, len(keys))
+	for i, k := range keys {
+		fields[i] = newField("", k, m[k])
+	}
+	ctx, _ = addFields(ctx, fields)
+	return ctx
+}
+
+func withFields(ctx context.Context, group string, args []any) context.Context {
+	ctx, _ = addFields(ctx, pairs(group, args))
+	return ctx
+}
+
+// pairs returns the fields of the key-value pairs with a string key.
+func pairs(group string, args []any) []field {
+	fields := make([]field, 0, len(args)/2)
+	for i := 0; i+1 < len(args); i += 2 {
+		if key, ok := args[i].(string); ok {
+			fields = append(fields, newField(group, key, args[i+1]))
+		}
+	}
+	return fields
+}
+
+// Attrs returns the log fields of ctx as they are added to records by Handler,
+// e.g. to forward them to error reports or metrics labels. The fields of
+// WithDebugValues are left out, see LevelAttrs.
+// The returned slice is a copy.
+func Attrs(ctx context.Context) []slog.Attr {
+	return fieldsFrom(ctx).withoutDebug().attrs(MaskValue)
+}
+
+// LevelAttrs returns the log fields of ctx as they are added to the records
+// of the level by Handler without options: the fields of WithDebugValues only
+// to Debug records. It is for the handlers rendering the fields themselves.
+// The returned slice is a copy.
+func LevelAttrs(ctx context.Context, level slog.Level) []slog.Attr {
+	d := fieldsFrom(ctx)
+	if d.len == 0 {
+		return nil
+	}
+	if level > slog.LevelDebug {
+		d = d.withoutDebug()
+	}
+	return d.attrs(MaskValue)
+}
+
+// GetValues returns all the values of the log fields of ctx with the key, in
+// the order they were added. The key of a field of WithGroupValues is
+// prefixed by its group and a dot, e.g. "http.request_id".
+func GetValues(ctx context.Context, key string) []any {
+	var xs []any
+	for _, f := range fieldsFrom(ctx).all() {
+		if f.is(key) {
+			xs = append(xs, f.any())
+		}
+	}
+	return xs
+}
+
+// GetFirstValue returns the first value of the log fields of ctx with the key.
+func GetFirstValue(ctx context.Context, key string) (any, bool) {
+	for _, f := range fieldsFrom(ctx).all() {
+		if f.is(key) {
+			return f.any(), true
+		}
+	}
+	return nil, false
+}
+
+// Len returns the number of log fields of ctx.
+func Len(ctx context.Context) int {
+	return fieldsFrom(ctx).len
+}
+
+// IsEmpty reports whether ctx has no log fields.
+func IsEmpty(ctx context.Context) bool {
+	return Len(ctx) == 0
+}
+
+// Range calls f for each log field of ctx in the order they were added, until f returns false.
+// Keys of the fields of WithGroupValues are prefixed by their group and a dot.
+func Range(ctx context.Context, f func(key string, val any) bool) {
+	for _, x := range fieldsFrom(ctx).all() {
+		if !f(x.name(), x.any()) {
+			return
+		}
+	}
+}
+
+// Detach returns a background context carrying the log fields, the logger and
+// the minimum level of ctx, but not its deadline, cancellation and other
+// values, e.g. for a goroutine outliving the request.
+func Detach(ctx context.Context) context.Context {
+	return CopyValues(ctx, context.Background())
+}
+
+// MergeContexts returns a copy of dst with the log fields of src added after
+// its own, e.g. to join a message consumer context with the fields of the
+// producer. The policy resolves the keys present in both: DedupNone keeps
+// both fields, DedupFirstWins the field of dst and DedupLastWins the field of src.
+func MergeContexts(dst, src context.Context, policy Dedup) context.Context {
+	from := fieldsFrom(src).all()
+	if len(from) == 0 {
+		return dst
+	}
+	to := fieldsFrom(dst).all()
+	names := func(fields []field) map[string]bool {
+		m := make(map[string]bool, len(fields))
+		for _, f := range fields {
+			m[f.name()] = true
+		}
+		return m
+	}
+	fields := make([]field, 0, len(to)+len(from))
+	switch policy {
+	case DedupFirstWins:
+		fields = append(fields, to...)
+		inDst := names(to)
+		for _, f := range from {
+			if !inDst[f.name()] {
+				fields = append(fields, f)
+			}
+		}
+	case DedupLastWins:
+		inSrc := names(from)
+		for _, f := range to {
+			if !inSrc[f.name()] {
+				fields = append(fields, f)
+			}
+		}
+		fields = append(fields, from...)
+	default:
+		fields = append(append(fields, to...), from...)
+	}
+	return context.WithValue(dst, keyFields, newFieldsData(fields))
+}
+
+// CopyValues returns a copy of to with the log fields, the logger and the
+// minimum level set in from, e.g. for worker pools to run the tasks with the
+// log values of the submitting context on their own long-lived context.
+func CopyValues(from, to context.Context) context.Context {
+	for _, key := range []any{keyFields, keyLogger, keyMinLevel} {
+		if v := from.Value(key); v != nil {
+			to = context.WithValue(to, key, v)
+		}
+	}
+	return to
+}
+
+// fieldsFrom returns the fields of ctx, empty if there are none.
+func fieldsFrom(ctx context.Context) *fieldsData {
+	if d, ok := ctx.Value(keyFields).(*fieldsData); ok {
+		return d
+	}
+	return &fieldsData{}
+}
+
+// newFieldsData returns the list of the fields.
+func newFieldsData(fields []field) *fieldsData {
+	return &fieldsData{added: fields, len: len(fields)}
+}
+
+// with returns the list with the fields added.
+func (d *fieldsData) with(added []field) *fieldsData {
+	if d.len == 0 {
+		return newFieldsData(added)
+	}
+	return &fieldsData{parent: d, added: added, len: d.len + len(added)}
+}
+
+// all returns all the fields in the order they were added.
+// The returned slice must not be modified.
+func (d *fieldsData) all() []field {
+	if d.parent == nil {
+		return d.added
+	}
+	if p := d.flat.Load(); p != nil {
+		return *p
+	}
+	fields := make([]field, d.len)
+	n := d.len
+	for x := d; x != nil; x = x.parent {
+		n -= len(x.added)
+		copy(fields[n:], x.added)
+	}
+	d.flat.Store(&fields)
+	return fields
+}
+
+func newField(group, key string, val any) field {
+	return field{group: group, attr: slog.Any(key, val), value: val}
+}
+
+// is reports whether the key is the key of the field, prefixed by its group if any.
+func (f field) is(key string) bool {
+	if f.group == "" {
+		return f.attr.Key == key
+	}
+	return len(key) == len(f.group)+1+len(f.attr.Key) &&
+		strings.HasPrefix(key, f.group) && key[len(f.group)] == '.' && strings.HasSuffix(key, f.attr.Key)
+}
+
+// record returns the attribute of the field added to records.
+func (f field) record(mask func(any) slog.Value) slog.Attr {
+	if f.sensitive {
+		return slog.Attr{Key: f.attr.Key, Value: mask(f.value)}
+	}
+	// resolve slog.LogValuer values, so the group values they return are
+	// expanded like the groups of record attributes
+	if f.attr.Value.Kind() == slog.KindLogValuer {
+		return slog.Attr{Key: f.attr.Key, Value: f.attr.Value.Resolve()}
+	}
+	return f.attr
+}
+
+// name returns the key of the field, prefixed by its group if any.
+func (f field) name() string {
+	if f.group == "" {
+		return f.attr.Key
+	}
+	return f.group + "." + f.attr.Key
+}
+
+// any returns the value of the field.
+func (f field) any() any {
+	return f.value
+}
+
+// withoutDebug returns the fields not added by WithDebugValues.
+func (d *fieldsData) withoutDebug() *fieldsData {
+	if !slices.ContainsFunc(d.all(), func(f field) bool { return f.debug }) {
+		return d
+	}
+	return newFieldsData(slices.DeleteFunc(slices.Clone(d.all()), func(f field) bool { return f.debug }))
+}
+
+// notCarried returns the fields of d not carried by the logger of FromContext
+// with the fields c: the debug fields of c and the fields added after c, if c
+// is d or an ancestor of d, otherwise all the fields.
+func (d *fieldsData) notCarried(c *fieldsData) *fieldsData {
+	for x := d; x != nil; x = x.parent {
+		if x == c {
+			fields := slices.DeleteFunc(slices.Clone(c.all()), func(f field) bool { return !f.debug })
+			return newFieldsData(append(fields, d.all()[c.len:]...))
+		}
+	}
+	return d
+}
+
+// rewrite returns the fields renamed, changed or dropped by f.
+func (d *fieldsData) rewrite(f func(key string, val any) (string, any, bool)) *fieldsData {
+	fields := make([]field, 0, d.len)
+	for _, x := range d.all() {
+		name := x.name()
+		key, val, ok := f(name, x.any())
+		if !ok {
+			continue
+		}
+		if key != name {
+			x.group = ""
+		} else {
+			key = x.attr.Key
+		}
+		x.attr, x.value = slog.Any(key, val), val
+		fields = append(fields, x)
+	}
+	return newFieldsData(fields)
+}
+
+// attrs returns the fields as attributes in the order they were added, the
+// fields of each group collected in a group attribute at the place of the
+// first one.
+// The values of sensitive fields are replaced by mask.
+func (d *fieldsData) attrs(mask func(any) slog.Value) []slog.Attr {
+	fields := d.all()
+	xs := make([]slog.Attr, 0, len(fields))
+	var done map[string]bool // groups already collected
+	for i, f := range fields {
+		if f.group == "" {
+			xs = append(xs, f.record(mask))
+			continue
+		}
+		if done[f.group] {
+			continue
+		}
+		if done == nil {
+			done = make(map[string]bool)
+		}
+		done[f.group] = true
+		var members []any
+		for _, g := range fields[i:] {
+			if g.group == f.group {
+				members = append(members, g.record(mask))
+			}
+		}
+		xs = append(xs, slog.Group(f.group, members...))
 	}
-	return context.WithValue(ctx, keyFields, v)
+	return xs
 }
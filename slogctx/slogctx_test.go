@@ -0,0 +1,207 @@
+package slogctx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// textHandler returns a text handler writing the records without time and level.
+func textHandler(w io.Writer) slog.Handler {
+	return slog.NewTextHandler(w, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if (a.Key == slog.TimeKey || a.Key == slog.LevelKey) && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+}
+
+func TestWithAttrValues(t *testing.T) {
+	ctx := WithAttrValues(context.Background(),
+		slog.Int("id", 7),
+		slog.Group("http", slog.String("method", "GET"), slog.Int("status", 200)),
+		slog.Any("user", user{42}))
+
+	buf := new(bytes.Buffer)
+	slog.New(NewHandler(textHandler(buf))).InfoContext(ctx, "x")
+	expected := "msg=x id=7 http.method=GET http.status=200 user.id=42\n"
+	if buf.String() != expected {
+		t.Fatalf("\n%s\nexpected\n%s", buf, expected)
+	}
+	if v, _ := GetFirstValue(ctx, "id"); v != int64(7) {
+		t.Fatalf("got %v", v)
+	}
+}
+
+func TestRange(t *testing.T) {
+	ctx := WithValues(context.Background(), "a", 1, "b", 2)
+	ctx = WithGroupValues(ctx, "http", "method", "GET")
+
+	var keys []string
+	var values []any
+	Range(ctx, func(key string, val any) bool {
+		keys, values = append(keys, key), append(values, val)
+		return true
+	})
+	if fmt.Sprint(keys, values) != "[a b http.method] [1 2 GET]" {
+		t.Fatalf("got %v %v", keys, values)
+	}
+
+	n := 0
+	Range(ctx, func(string, any) bool {
+		n++
+		return false
+	})
+	if n != 1 {
+		t.Fatalf("called %d times after false", n)
+	}
+	Range(context.Background(), func(string, any) bool {
+		t.Fatal("called without fields")
+		return true
+	})
+}
+
+func TestWithValueMap(t *testing.T) {
+	ctx := WithValues(context.Background(), "app", "api")
+	ctx = WithValueMap(ctx, map[string]any{"c": 3, "a": 1, "b": 2})
+	if s := fmt.Sprint(Attrs(ctx)); s != "[app=api a=1 b=2 c=3]" {
+		t.Fatalf("got %s", s)
+	}
+	if Len(WithValueMap(ctx, nil)) != 4 {
+		t.Fatal("fields changed by an empty map")
+	}
+}
+
+func TestWithValuesStrict(t *testing.T) {
+	ctx := WithValues(context.Background(), "app", "api")
+	for _, args := range [][]any{
+		{"a", 1, "b"},
+		{"a", 1, 2, 3},
+		{"", 1},
+	} {
+		got, err := WithValuesStrict(ctx, args...)
+		if !errors.Is(err, ErrInvalidValues) || got != ctx {
+			t.Errorf("%v: got %v", args, err)
+		}
+	}
+	got, err := WithValuesStrict(ctx, "a", 1, "b", 2)
+	if err != nil || fmt.Sprint(Attrs(got)) != "[app=api a=1 b=2]" {
+		t.Fatalf("got %v %v", Attrs(got), err)
+	}
+	// WithValues ignores the malformed pairs
+	if s := fmt.Sprint(Attrs(WithValues(ctx, "a", 1, 2, 3, "b"))); s != "[app=api a=1]" {
+		t.Fatalf("got %s", s)
+	}
+}
+
+type otherKey struct{}
+
+func TestDetach(t *testing.T) {
+	logger := slog.New(textHandler(io.Discard))
+	parent, cancel := context.WithTimeout(context.Background(), time.Hour)
+	parent = context.WithValue(parent, otherKey{}, "other")
+	parent = WithMinLevel(NewContext(parent, logger), slog.LevelDebug)
+	parent = WithValues(parent, "request_id", "r1")
+
+	ctx := Detach(parent)
+	cancel()
+	if ctx.Err() != nil {
+		t.Fatal("canceled with the parent")
+	}
+	if _, ok := ctx.Deadline(); ok || ctx.Value(otherKey{}) != nil {
+		t.Fatal("deadline or other values kept")
+	}
+	if level, ok := MinLevel(ctx); !ok || level != slog.LevelDebug || Log(ctx) != logger {
+		t.Fatal("logger or minimum level lost")
+	}
+	if s := fmt.Sprint(Attrs(ctx)); s != "[request_id=r1]" {
+		t.Fatalf("got %s", s)
+	}
+}
+
+func TestMergeContexts(t *testing.T) {
+	dst := WithValues(context.Background(), "id", 1, "consumer", "c")
+	src := WithValues(context.Background(), "producer", "p", "id", 2)
+	for policy, expected := range map[Dedup]string{
+		DedupNone:      "[id=1 consumer=c producer=p id=2]",
+		DedupFirstWins: "[id=1 consumer=c producer=p]",
+		DedupLastWins:  "[consumer=c producer=p id=2]",
+	} {
+		if s := fmt.Sprint(Attrs(MergeContexts(dst, src, policy))); s != expected {
+			t.Errorf("policy %d: got %s, expected %s", policy, s, expected)
+		}
+	}
+	if MergeContexts(dst, context.Background(), DedupNone) != dst {
+		t.Fatal("dst changed without src fields")
+	}
+}
+
+func TestCopyValues(t *testing.T) {
+	from := WithValues(NewContext(context.Background(), slog.New(textHandler(io.Discard))), "task", 7)
+	to, cancel := context.WithCancel(context.WithValue(context.Background(), otherKey{}, "worker"))
+	ctx := CopyValues(from, to)
+	if s := fmt.Sprint(Attrs(ctx)); s != "[task=7]" || Log(ctx) != Log(from) {
+		t.Fatalf("got %s", s)
+	}
+	if ctx.Value(otherKey{}) != "worker" {
+		t.Fatal("values of to lost")
+	}
+	cancel()
+	if ctx.Err() == nil {
+		t.Fatal("cancellation of to lost")
+	}
+	if CopyValues(context.Background(), to) != to {
+		t.Fatal("to changed without values")
+	}
+}
+
+func TestLen(t *testing.T) {
+	ctx := context.Background()
+	if Len(ctx) != 0 || !IsEmpty(ctx) {
+		t.Fatalf("%d fields without fields", Len(ctx))
+	}
+	ctx = WithValues(ctx, "a", 1, "b", 2)
+	ctx = WithGroupValues(ctx, "http", "method", "GET")
+	ctx = WithValues(ctx, "a", 3)
+	if Len(ctx) != 4 || IsEmpty(ctx) {
+		t.Fatalf("got %d fields, expected 4", Len(ctx))
+	}
+	if ctx = WithoutKeysMatching(ctx, func(string) bool { return true }); Len(ctx) != 0 || !IsEmpty(ctx) {
+		t.Fatalf("got %d fields after removing all", Len(ctx))
+	}
+}
+
+func TestWithoutKeysMatching(t *testing.T) {
+	ctx := WithValues(context.Background(), "tmp_a", 1, "id", 2)
+	ctx = WithGroupValues(ctx, "debug", "x", 3)
+	ctx = WithGroupValues(ctx, "http", "tmp_b", 4)
+
+	if WithoutKeysMatching(ctx, func(string) bool { return false }) != ctx {
+		t.Fatal("context copied without matching keys")
+	}
+
+	buf := new(bytes.Buffer)
+	logger := slog.New(NewHandler(textHandler(buf)))
+	logger.InfoContext(WithoutKeysMatchingRegexp(ctx, regexp.MustCompile(`^(tmp|debug)_`)), "x")
+	logger.InfoContext(WithoutKeysMatchingRegexp(ctx, regexp.MustCompile(`^debug\.|tmp_`)), "y")
+	logger.InfoContext(WithoutKeysMatching(ctx, func(key string) bool { return key == "http.tmp_b" }), "z")
+
+	expected := `msg=x id=2 debug.x=3 http.tmp_b=4
+msg=y id=2
+msg=z tmp_a=1 id=2 debug.x=3
+`
+	if buf.String() != expected {
+		t.Fatalf("\n%s\nexpected\n%s", buf, expected)
+	}
+	if Len(ctx) != 4 {
+		t.Fatalf("source context changed, %d fields", Len(ctx))
+	}
+}
@@ -0,0 +1,118 @@
+package slogctx
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+type (
+	// Fields is a snapshot of the log fields of a context taken by Snapshot.
+	// It marshals to JSON, e.g. to pass the fields in a queue message to
+	// another process, where WithSnapshot restores them. The values must be
+	// JSON-marshalable themselves and are restored as decoded by encoding/json,
+	// except the values of the slog kinds, which are restored to their kind.
+	// The integers and durations are kept as strings to survive the float64
+	// of the JSON numbers. Sensitive fields are left out, as by InjectHTTP.
+	Fields []Field
+
+	// Field is a log field of Fields.
+	Field struct {
+		Group string `json:"group,omitempty"`
+		Key   string `json:"key"`
+		Value any    `json:"value,omitempty"`
+		Kind  string `json:"kind,omitempty"`  // of the slog value, but slog.KindAny
+		Attrs Fields `json:"attrs,omitempty"` // of the group value of slog.KindGroup
+		Debug bool   `json:"debug,omitempty"`
+	}
+)
+
+// Snapshot returns the log fields of ctx.
+func Snapshot(ctx context.Context) Fields {
+	var fields Fields
+	for _, f := range fieldsFrom(ctx).all() {
+		if f.sensitive {
+			continue
+		}
+		x := snapshotAttr(f.attr)
+		x.Group = f.group
+		x.Debug = f.debug
+		fields = append(fields, x)
+	}
+	return fields
+}
+
+// WithSnapshot returns a copy of ctx with the fields added to its log fields.
+func WithSnapshot(ctx context.Context, fields Fields) context.Context {
+	added := make([]field, len(fields))
+	for i, f := range fields {
+		if f.Kind == "" {
+			added[i] = newField(f.Group, f.Key, f.Value)
+		} else {
+			a := f.attr()
+			added[i] = field{group: f.Group, attr: a, value: a.Value.Any()}
+		}
+		added[i].debug = f.Debug
+	}
+	ctx, _ = addFields(ctx, added)
+	return ctx
+}
+
+// snapshotAttr returns the field of the attribute, with its group members
+// as fields of their own.
+func snapshotAttr(a slog.Attr) Field {
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindAny:
+		return Field{Key: a.Key, Value: v.Any()}
+	case slog.KindGroup:
+		group := v.Group()
+		attrs := make(Fields, len(group))
+		for i, a := range group {
+			attrs[i] = snapshotAttr(a)
+		}
+		return Field{Key: a.Key, Kind: v.Kind().String(), Attrs: attrs}
+	case slog.KindInt64:
+		return Field{Key: a.Key, Value: strconv.FormatInt(v.Int64(), 10), Kind: v.Kind().String()}
+	case slog.KindDuration:
+		return Field{Key: a.Key, Value: strconv.FormatInt(int64(v.Duration()), 10), Kind: v.Kind().String()}
+	case slog.KindUint64:
+		return Field{Key: a.Key, Value: strconv.FormatUint(v.Uint64(), 10), Kind: v.Kind().String()}
+	}
+	return Field{Key: a.Key, Value: v.Any(), Kind: v.Kind().String()}
+}
+
+// attr returns the attribute of the field, the value converted to the kind
+// from its JSON decoded form if needed.
+func (f Field) attr() slog.Attr {
+	if f.Kind == slog.KindGroup.String() {
+		attrs := make([]slog.Attr, len(f.Attrs))
+		for i, x := range f.Attrs {
+			attrs[i] = x.attr()
+		}
+		return slog.Attr{Key: f.Key, Value: slog.GroupValue(attrs...)}
+	}
+	v := slog.AnyValue(f.Value)
+	if x, ok := f.Value.(string); ok {
+		switch f.Kind {
+		case slog.KindInt64.String():
+			if n, err := strconv.ParseInt(x, 10, 64); err == nil {
+				v = slog.Int64Value(n)
+			}
+		case slog.KindUint64.String():
+			if n, err := strconv.ParseUint(x, 10, 64); err == nil {
+				v = slog.Uint64Value(n)
+			}
+		case slog.KindDuration.String():
+			if n, err := strconv.ParseInt(x, 10, 64); err == nil {
+				v = slog.DurationValue(time.Duration(n))
+			}
+		case slog.KindTime.String():
+			if t, err := time.Parse(time.RFC3339Nano, x); err == nil {
+				v = slog.TimeValue(t)
+			}
+		}
+	}
+	return slog.Attr{Key: f.Key, Value: v}
+}
@@ -0,0 +1,41 @@
+package slogctx
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// WithString returns a copy of ctx with the string log field.
+func WithString(ctx context.Context, key, value string) context.Context {
+	return WithAttrValues(ctx, slog.String(key, value))
+}
+
+// WithInt returns a copy of ctx with the int log field.
+func WithInt(ctx context.Context, key string, value int) context.Context {
+	return WithAttrValues(ctx, slog.Int(key, value))
+}
+
+// WithInt64 returns a copy of ctx with the int64 log field.
+func WithInt64(ctx context.Context, key string, value int64) context.Context {
+	return WithAttrValues(ctx, slog.Int64(key, value))
+}
+
+// WithBool returns a copy of ctx with the bool log field.
+func WithBool(ctx context.Context, key string, value bool) context.Context {
+	return WithAttrValues(ctx, slog.Bool(key, value))
+}
+
+// WithDuration returns a copy of ctx with the time.Duration log field.
+func WithDuration(ctx context.Context, key string, value time.Duration) context.Context {
+	return WithAttrValues(ctx, slog.Duration(key, value))
+}
+
+// WithErr returns a copy of ctx with the error as the "error" log field.
+// A nil error adds no field.
+func WithErr(ctx context.Context, err error) context.Context {
+	if err == nil {
+		return ctx
+	}
+	return WithAttrValues(ctx, slog.Any("error", err))
+}
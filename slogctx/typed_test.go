@@ -0,0 +1,30 @@
+package slogctx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestTypedSetters(t *testing.T) {
+	ctx := WithString(context.Background(), "s", "v")
+	ctx = WithInt(ctx, "i", 1)
+	ctx = WithInt64(ctx, "i64", 2)
+	ctx = WithBool(ctx, "b", true)
+	ctx = WithDuration(ctx, "d", time.Second)
+	ctx = WithErr(ctx, nil)
+	ctx = WithErr(ctx, errors.New("failed"))
+
+	buf := new(bytes.Buffer)
+	slog.New(NewHandler(textHandler(buf))).InfoContext(ctx, "x")
+	expected := "msg=x s=v i=1 i64=2 b=true d=1s error=failed\n"
+	if buf.String() != expected {
+		t.Fatalf("\n%s\nexpected\n%s", buf, expected)
+	}
+	if v, _ := GetFirstValue(ctx, "d"); v != time.Second {
+		t.Fatalf("got %v", v)
+	}
+}
@@ -0,0 +1,256 @@
+package slogx
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+)
+
+// SocketOptions configures SocketHandler. Zero values select the defaults.
+type SocketOptions struct {
+	slog.HandlerOptions
+
+	DialTimeout     time.Duration // 5s by default
+	WriteTimeout    time.Duration // 5s by default
+	ReconnectDelay  time.Duration // minimum interval between dial attempts, 1s by default
+	RetryBufferSize int           // bytes of records kept while disconnected, 1MiB by default
+	Clock           Clock         // the system clock by default
+
+	// Fallback receives the records that could not be written to the socket,
+	// instead of the retry buffer. It remains owned by the caller:
+	// SocketHandler.Close does not close it
+	Fallback slog.Handler
+}
+
+// SocketHandler writes records as newline-delimited JSON to a TCP or UDP
+// endpoint such as Logstash, Vector or Fluent Bit.
+// The connection is established lazily and re-established after failures.
+// Records written while the endpoint is unreachable are kept in a bounded
+// retry buffer, oldest dropped first, and sent before the next record once
+// the connection is back, or passed to the fallback handler if there is one,
+// so each record is delivered once.
+type SocketHandler struct {
+	json     slog.Handler
+	fallback slog.Handler
+	conn     *socketConn
+}
+
+var (
+	_ slog.Handler  = (*SocketHandler)(nil)
+	_ StatsProvider = (*SocketHandler)(nil)
+)
+
+var errSocketDisconnected = errors.New("slogx: socket is disconnected")
+
+// NewSocketHandler creates SocketHandler for the network ("tcp", "udp" and
+// their variants) and address.
+func NewSocketHandler(network, addr string, opts *SocketOptions) *SocketHandler {
+	if opts == nil {
+		opts = &SocketOptions{}
+	}
+	c := &socketConn{
+		network:        network,
+		addr:           addr,
+		dialTimeout:    opts.DialTimeout,
+		writeTimeout:   opts.WriteTimeout,
+		reconnectDelay: opts.ReconnectDelay,
+		maxPending:     opts.RetryBufferSize,
+		fallback:       opts.Fallback != nil,
+		clock:          clockOrSystem(opts.Clock),
+	}
+	if c.dialTimeout <= 0 {
+		c.dialTimeout = 5 * time.Second
+	}
+	if c.writeTimeout <= 0 {
+		c.writeTimeout = 5 * time.Second
+	}
+	if c.reconnectDelay <= 0 {
+		c.reconnectDelay = time.Second
+	}
+	if c.maxPending <= 0 {
+		c.maxPending = 1 << 20
+	}
+	return &SocketHandler{
+		json:     slog.NewJSONHandler(c, &opts.HandlerOptions),
+		fallback: opts.Fallback,
+		conn:     c,
+	}
+}
+
+func (h *SocketHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.json.Enabled(ctx, level)
+}
+
+func (h *SocketHandler) Handle(ctx context.Context, r slog.Record) error {
+	err := h.json.Handle(ctx, r)
+	if err != nil && h.fallback != nil && h.fallback.Enabled(ctx, r.Level) {
+		return h.fallback.Handle(ctx, r)
+	}
+	return err
+}
+
+func (h *SocketHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.json = h.json.WithAttrs(attrs)
+	if h.fallback != nil {
+		h2.fallback = h.fallback.WithAttrs(attrs)
+	}
+	return &h2
+}
+
+func (h *SocketHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.json = h.json.WithGroup(name)
+	if h.fallback != nil {
+		h2.fallback = h.fallback.WithGroup(name)
+	}
+	return &h2
+}
+
+// Stats returns the delivery counters of the handler. Records delivered from
+// the retry buffer are counted as retried, not handled.
+func (h *SocketHandler) Stats() Stats {
+	return h.conn.Stats()
+}
+
+func (h *SocketHandler) sharedState() any {
+	return h.conn
+}
+
+// Connected reports whether the handler is connected to the address.
+func (h *SocketHandler) Connected() bool {
+	return h.conn.connected()
+}
+
+// Close closes the connection. Records kept in the retry buffer are discarded.
+func (h *SocketHandler) Close() error {
+	return h.conn.close()
+}
+
+type socketConn struct {
+	network, addr  string
+	dialTimeout    time.Duration
+	writeTimeout   time.Duration
+	reconnectDelay time.Duration
+	maxPending     int
+	fallback       bool // the failed records are left to the fallback handler
+	clock          Clock
+
+	mu           sync.Mutex
+	conn         net.Conn
+	nextDial     time.Time
+	pending      [][]byte
+	pendingBytes int
+
+	statsCounters
+}
+
+func (c *socketConn) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.connect(); err != nil {
+		c.keep(p)
+		return 0, err
+	}
+	for len(c.pending) > 0 {
+		if err := c.send(c.pending[0]); err != nil {
+			c.keep(p)
+			return 0, err
+		}
+		c.retried.Add(1)
+		c.pendingBytes -= len(c.pending[0])
+		c.pending[0] = nil
+		c.pending = c.pending[1:]
+	}
+	if err := c.send(p); err != nil {
+		c.keep(p)
+		return 0, err
+	}
+	c.handled.Add(1)
+	c.setFlushed(c.clock.Now())
+	return len(p), nil
+}
+
+// Stats returns the delivery counters with the number of records in the retry buffer.
+func (c *socketConn) Stats() Stats {
+	s := c.statsCounters.Stats()
+	c.mu.Lock()
+	s.Queued = len(c.pending)
+	c.mu.Unlock()
+	return s
+}
+
+func (c *socketConn) connected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn != nil
+}
+
+func (c *socketConn) connect() error {
+	if c.conn != nil {
+		return nil
+	}
+	now := c.clock.Now()
+	if now.Before(c.nextDial) {
+		return errSocketDisconnected
+	}
+	conn, err := net.DialTimeout(c.network, c.addr, c.dialTimeout)
+	if err != nil {
+		c.nextDial = now.Add(c.reconnectDelay)
+		c.setError(err, now)
+		return err
+	}
+	c.conn = conn
+	return nil
+}
+
+func (c *socketConn) send(p []byte) error {
+	_ = c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+	_, err := c.conn.Write(p)
+	if err != nil {
+		now := c.clock.Now()
+		c.setError(err, now)
+		_ = c.conn.Close()
+		c.conn = nil
+		c.nextDial = now.Add(c.reconnectDelay)
+	}
+	return err
+}
+
+// keep appends a copy of p to the retry buffer, dropping the oldest records
+// when the buffer is full, unless the record is left to the fallback handler.
+func (c *socketConn) keep(p []byte) {
+	if c.fallback {
+		return
+	}
+	if len(p) > c.maxPending {
+		c.dropped.Add(1)
+		return
+	}
+	for c.pendingBytes+len(p) > c.maxPending {
+		c.pendingBytes -= len(c.pending[0])
+		c.pending[0] = nil
+		c.pending = c.pending[1:]
+		c.dropped.Add(1)
+	}
+	c.pending = append(c.pending, append([]byte(nil), p...))
+	c.pendingBytes += len(p)
+}
+
+func (c *socketConn) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.dropped.Add(uint64(len(c.pending)))
+	c.pending, c.pendingBytes = nil, 0
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
@@ -0,0 +1,113 @@
+package slogx
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSocketHandler(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	h := NewSocketHandler("tcp", ln.Addr().String(), nil)
+	defer h.Close()
+	slog.New(h).Info("hello", "n", 1)
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(line, `"msg":"hello","n":1`) {
+		t.Fatalf("unexpected line %q", line)
+	}
+}
+
+func TestSocketHandlerFallback(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	buf := new(bytes.Buffer)
+	h := NewSocketHandler("tcp", addr, &SocketOptions{
+		Fallback: slog.NewTextHandler(buf, nil),
+	})
+	logger := slog.New(h).With("app", "test")
+	logger.Info("first")
+	logger.Info("second")
+
+	if s := buf.String(); !strings.Contains(s, "msg=first app=test") || !strings.Contains(s, "msg=second app=test") {
+		t.Fatalf("unexpected fallback output %q", s)
+	}
+	if n := len(h.conn.pending); n != 0 {
+		t.Fatalf("%d records in retry buffer, expected none", n)
+	}
+	if s := h.Stats(); s.Handled != 0 || s.LastError == nil {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+}
+
+func TestSocketHandlerReconnect(t *testing.T) {
+	for _, fallback := range []bool{false, true} {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr := ln.Addr().String()
+		_ = ln.Close()
+
+		buf := new(bytes.Buffer)
+		opts := &SocketOptions{ReconnectDelay: time.Nanosecond}
+		if fallback {
+			opts.Fallback = slog.NewTextHandler(buf, nil)
+		}
+		h := NewSocketHandler("tcp", addr, opts)
+		logger := slog.New(h)
+		logger.Info("first")
+
+		if ln, err = net.Listen("tcp", addr); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+		logger.Info("second")
+		_ = h.Close()
+
+		conn, err := ln.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		received, err := io.ReadAll(conn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = conn.Close()
+		_ = ln.Close()
+
+		for _, msg := range []string{"first", "second"} {
+			sent := strings.Count(string(received), `"msg":"`+msg+`"`)
+			fell := strings.Count(buf.String(), "msg="+msg)
+			if sent+fell != 1 {
+				t.Errorf("fallback %v: %q delivered %d times to the socket and %d times to the fallback", fallback, msg, sent, fell)
+			}
+		}
+		if fallback && !strings.Contains(buf.String(), "msg=first") {
+			t.Errorf("first record not passed to the fallback: %q", buf.String())
+		}
+	}
+}
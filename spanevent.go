@@ -0,0 +1,136 @@
+package slogx
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Attributes of the span events added by SpanEventHandler, following the
+// OpenTelemetry log semantic conventions.
+const (
+	SpanEventName        = "log"
+	SpanEventSeverityKey = "log.severity"
+	SpanEventMessageKey  = "log.message"
+)
+
+type (
+	// SpanEventOptions configures SpanEventHandler.
+	SpanEventOptions struct {
+		// Level is the minimum level of the records added as span events, Info by default
+		Level slog.Leveler
+
+		// AddEvent adds the event to the active span of the context, usually a
+		// thin adapter over the OpenTelemetry API:
+		//
+		//	func(ctx context.Context, name string, t time.Time, attrs []slog.Attr) {
+		//		span := trace.SpanFromContext(ctx)
+		//		if !span.IsRecording() {
+		//			return
+		//		}
+		//		kvs := make([]attribute.KeyValue, len(attrs))
+		//		for i, a := range attrs {
+		//			kvs[i] = toKeyValue(a) // by a.Value.Kind()
+		//		}
+		//		span.AddEvent(name, trace.WithTimestamp(t), trace.WithAttributes(kvs...))
+		//	}
+		//
+		// The attributes are flat: group keys are joined by dots, and the values
+		// are strings, int64, float64 or bool, other kinds are formatted as strings.
+		AddEvent func(ctx context.Context, name string, t time.Time, attrs []slog.Attr)
+	}
+
+	// SpanEventHandler passes records to the inner handler and also adds them
+	// as events to the active trace span, so traces show the logs of the
+	// request inline without a separate log backend.
+	SpanEventHandler struct {
+		inner slog.Handler
+		opts  SpanEventOptions
+		state attrState
+	}
+)
+
+var _ slog.Handler = (*SpanEventHandler)(nil)
+
+// NewSpanEventHandler creates SpanEventHandler wrapping inner
+func NewSpanEventHandler(inner slog.Handler, opts SpanEventOptions) *SpanEventHandler {
+	if opts.Level == nil {
+		opts.Level = slog.LevelInfo
+	}
+	return &SpanEventHandler{inner: inner, opts: opts}
+}
+
+// Unwrap returns the inner handler.
+func (h *SpanEventHandler) Unwrap() slog.Handler {
+	return h.inner
+}
+
+// Enabled reports whether the records of the level are added as span events
+// or the inner handler is enabled for the level.
+func (h *SpanEventHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.eventEnabled(level) || h.inner.Enabled(ctx, level)
+}
+
+func (h *SpanEventHandler) Handle(ctx context.Context, r slog.Record) error {
+	if h.eventEnabled(r.Level) {
+		t := r.Time
+		if t.IsZero() {
+			t = time.Now()
+		}
+		attrs := []slog.Attr{
+			slog.String(SpanEventSeverityKey, r.Level.String()),
+			slog.String(SpanEventMessageKey, r.Message),
+		}
+		attrs = appendFlatAttrs(attrs, "", h.state.attrs(r, nil))
+		h.opts.AddEvent(ctx, SpanEventName, t, attrs)
+	}
+	if !h.inner.Enabled(ctx, r.Level) {
+		return nil
+	}
+	return h.inner.Handle(ctx, r)
+}
+
+func (h *SpanEventHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.inner = h.inner.WithAttrs(attrs)
+	h2.state = h.state.withAttrs(attrs)
+	return &h2
+}
+
+func (h *SpanEventHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.inner = h.inner.WithGroup(name)
+	h2.state = h.state.withGroup(name)
+	return &h2
+}
+
+func (h *SpanEventHandler) eventEnabled(level slog.Level) bool {
+	return h.opts.AddEvent != nil && level >= h.opts.Level.Level()
+}
+
+// appendFlatAttrs appends the resolved attributes with group keys joined by
+// dots and values of the kinds OpenTelemetry attributes support.
+func appendFlatAttrs(xs []slog.Attr, prefix string, attrs []slog.Attr) []slog.Attr {
+	for _, a := range attrs {
+		key := prefix + a.Key
+		switch a.Value.Kind() {
+		case slog.KindGroup:
+			xs = appendFlatAttrs(xs, key+".", a.Value.Group())
+		case slog.KindString, slog.KindInt64, slog.KindFloat64, slog.KindBool:
+			xs = append(xs, slog.Attr{Key: key, Value: a.Value})
+		case slog.KindUint64:
+			if v := a.Value.Uint64(); v <= 1<<63-1 {
+				xs = append(xs, slog.Int64(key, int64(v)))
+			} else {
+				xs = append(xs, slog.String(key, a.Value.String()))
+			}
+		case slog.KindDuration:
+			xs = append(xs, slog.Int64(key, int64(a.Value.Duration())))
+		case slog.KindTime:
+			xs = append(xs, slog.String(key, a.Value.Time().Format(time.RFC3339Nano)))
+		default:
+			xs = append(xs, slog.String(key, a.Value.String()))
+		}
+	}
+	return xs
+}
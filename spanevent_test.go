@@ -0,0 +1,63 @@
+package slogx
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+type spanEvent struct {
+	name  string
+	time  time.Time
+	attrs []slog.Attr
+}
+
+func TestSpanEventHandler(t *testing.T) {
+	var events []spanEvent
+	buf := new(bytes.Buffer)
+	h := NewSpanEventHandler(routeTextHandler(buf), SpanEventOptions{
+		AddEvent: func(_ context.Context, name string, t time.Time, attrs []slog.Attr) {
+			events = append(events, spanEvent{name, t, attrs})
+		},
+	})
+	logger := slog.New(h).With("app", "api").WithGroup("req")
+
+	tm := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	logger.Debug("hidden")
+	logger.Info("done", "n", uint64(1<<63), "ms", 120*time.Millisecond, "at", tm, "ok", true, slog.Group("user", "id", 7))
+
+	if buf.String() != "msg=done app=api req.n=9223372036854775808 req.ms=120ms req.at=2024-01-02T03:04:05.000Z req.ok=true req.user.id=7\n" {
+		t.Fatalf("got\n%s", buf)
+	}
+	if len(events) != 1 || events[0].name != SpanEventName || events[0].time.IsZero() {
+		t.Fatalf("unexpected events %v", events)
+	}
+	expected := "[log.severity=INFO log.message=done app=api req.n=9223372036854775808 req.ms=120000000 req.at=2024-01-02T03:04:05Z req.ok=true req.user.id=7]"
+	if s := fmt.Sprint(events[0].attrs); s != expected {
+		t.Fatalf("\n%s\nexpected\n%s", s, expected)
+	}
+}
+
+func TestSpanEventHandlerLevels(t *testing.T) {
+	var events int
+	buf := new(bytes.Buffer)
+	h := NewSpanEventHandler(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelError}), SpanEventOptions{
+		Level: slog.LevelDebug,
+		AddEvent: func(context.Context, string, time.Time, []slog.Attr) {
+			events++
+		},
+	})
+	if !h.Enabled(context.Background(), slog.LevelDebug) {
+		t.Fatal("disabled for span events")
+	}
+	slog.New(h).Debug("event only")
+	if events != 1 || buf.Len() != 0 {
+		t.Fatalf("%d events, logged %s", events, buf)
+	}
+	if NewSpanEventHandler(slog.NewTextHandler(buf, nil), SpanEventOptions{}).Enabled(context.Background(), slog.LevelDebug) {
+		t.Fatal("enabled without AddEvent")
+	}
+}
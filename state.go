@@ -0,0 +1,140 @@
+package slogx
+
+import (
+	"log/slog"
+	"runtime"
+	"slices"
+)
+
+type (
+	// groupOrAttrs is a single WithGroup or WithAttrs call made on a handler.
+	groupOrAttrs struct {
+		group string
+		attrs []slog.Attr
+	}
+
+	// attrState keeps the WithAttrs/WithGroup history of a handler, so record
+	// attributes can be nested in the right groups when the record is handled.
+	attrState struct {
+		goas []groupOrAttrs
+	}
+)
+
+func (s attrState) withGroup(name string) attrState {
+	if name == "" {
+		return s
+	}
+	return attrState{goas: append(slices.Clip(s.goas), groupOrAttrs{group: name})}
+}
+
+func (s attrState) withAttrs(attrs []slog.Attr) attrState {
+	if len(attrs) == 0 {
+		return s
+	}
+	return attrState{goas: append(slices.Clip(s.goas), groupOrAttrs{attrs: slices.Clone(attrs)})}
+}
+
+// attrs returns handler and record attributes nested in their groups,
+// with values resolved, replace applied and empty groups removed.
+func (s attrState) attrs(r slog.Record, replace func([]string, slog.Attr) slog.Attr) []slog.Attr {
+	rec := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		rec = append(rec, a)
+		return true
+	})
+	return s.nest(0, nil, rec, replace)
+}
+
+func (s attrState) nest(i int, groups []string, rec []slog.Attr, replace func([]string, slog.Attr) slog.Attr) []slog.Attr {
+	var xs []slog.Attr
+	for ; i < len(s.goas); i++ {
+		g := s.goas[i]
+		if g.group == "" {
+			xs = appendResolvedAttrs(xs, groups, g.attrs, replace)
+			continue
+		}
+		inner := s.nest(i+1, append(slices.Clip(groups), g.group), rec, replace)
+		if len(inner) > 0 {
+			xs = append(xs, slog.Attr{Key: g.group, Value: slog.GroupValue(inner...)})
+		}
+		return xs
+	}
+	return appendResolvedAttrs(xs, groups, rec, replace)
+}
+
+func appendResolvedAttrs(xs []slog.Attr, groups []string, attrs []slog.Attr, replace func([]string, slog.Attr) slog.Attr) []slog.Attr {
+	for _, a := range attrs {
+		a.Value = a.Value.Resolve()
+		if a.Value.Kind() == slog.KindGroup {
+			if a.Key == "" {
+				xs = appendResolvedAttrs(xs, groups, a.Value.Group(), replace)
+				continue
+			}
+			inner := appendResolvedAttrs(nil, append(slices.Clip(groups), a.Key), a.Value.Group(), replace)
+			if len(inner) > 0 {
+				xs = append(xs, slog.Attr{Key: a.Key, Value: slog.GroupValue(inner...)})
+			}
+			continue
+		}
+		if replace != nil {
+			a = replace(groups, a)
+			a.Value = a.Value.Resolve()
+		}
+		if a.Equal(slog.Attr{}) {
+			continue
+		}
+		xs = append(xs, a)
+	}
+	return xs
+}
+
+// builtinAttrs returns the time, level, source and message attributes of the
+// record, with ReplaceAttr of opts and then rename, if any, applied.
+// The source is returned as a group.
+func builtinAttrs(r slog.Record, opts *slog.HandlerOptions, rename func(slog.Attr) slog.Attr) []slog.Attr {
+	xs := make([]slog.Attr, 0, 4)
+	if !r.Time.IsZero() {
+		xs = append(xs, slog.Time(slog.TimeKey, r.Time))
+	}
+	xs = append(xs, slog.Any(slog.LevelKey, r.Level))
+	if opts.AddSource && r.PC != 0 {
+		src := recordSource(r)
+		xs = append(xs, slog.Any(slog.SourceKey, &src))
+	}
+	xs = append(xs, slog.String(slog.MessageKey, r.Message))
+
+	ys := xs[:0]
+	for _, a := range xs {
+		if opts.ReplaceAttr != nil {
+			a = opts.ReplaceAttr(nil, a)
+			if a.Equal(slog.Attr{}) {
+				continue
+			}
+		}
+		if rename != nil {
+			a = rename(a)
+		}
+		if src, ok := a.Value.Any().(*slog.Source); ok {
+			a.Value = slog.GroupValue(
+				slog.String("function", src.Function),
+				slog.String("file", src.File),
+				slog.Int("line", src.Line))
+		}
+		ys = append(ys, a)
+	}
+	return ys
+}
+
+func recordSource(r slog.Record) slog.Source {
+	fs := runtime.CallersFrames([]uintptr{r.PC})
+	f, _ := fs.Next()
+	return slog.Source{Function: f.Function, File: f.File, Line: f.Line}
+}
+
+func levelEnabled(opts *slog.HandlerOptions, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if opts.Level != nil {
+		minLevel = opts.Level.Level()
+	}
+	return level >= minLevel
+}
@@ -0,0 +1,104 @@
+package slogx
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a snapshot of the counters of a handler delivering records
+// asynchronously, in batches or over the network.
+type Stats struct {
+	Handled       uint64 // records delivered
+	Dropped       uint64 // records lost
+	Retried       uint64 // records delivered after a failed attempt
+	Queued        int    // records waiting for delivery
+	LastError     error
+	LastErrorTime time.Time
+	LastFlushTime time.Time // time of the last successful delivery
+}
+
+// StatsProvider is implemented by handlers which count the records they deliver.
+type StatsProvider interface {
+	Stats() Stats
+}
+
+func (s Stats) LogValue() slog.Value {
+	attrs := []slog.Attr{
+		slog.Uint64("handled", s.Handled),
+		slog.Uint64("dropped", s.Dropped),
+		slog.Uint64("retried", s.Retried),
+		slog.Int("queued", s.Queued),
+	}
+	if !s.LastFlushTime.IsZero() {
+		attrs = append(attrs, slog.Time("last_flush_time", s.LastFlushTime))
+	}
+	if s.LastError != nil {
+		attrs = append(attrs,
+			slog.String("last_error", s.LastError.Error()),
+			slog.Time("last_error_time", s.LastErrorTime))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// LogStats logs the stats of p with the attrs every interval until ctx is done.
+// The record level is Warn when records were dropped since the previous record, otherwise Info.
+//
+//	go slogx.LogStats(ctx, logger, time.Minute, socketHandler, "sink", "logstash")
+func LogStats(ctx context.Context, logger *slog.Logger, interval time.Duration, p StatsProvider, attrs ...any) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var prev Stats
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		s := p.Stats()
+		level := slog.LevelInfo
+		if s.Dropped > prev.Dropped {
+			level = slog.LevelWarn
+		}
+		logger.Log(ctx, level, "logging pipeline stats", append(attrs, slog.Any("stats", s))...)
+		prev = s
+	}
+}
+
+// statsCounters is embedded by handlers implementing StatsProvider.
+type statsCounters struct {
+	handled, dropped, retried atomic.Uint64
+
+	mu          sync.Mutex
+	lastErr     error
+	lastErrTime time.Time
+	lastFlush   time.Time
+}
+
+func (c *statsCounters) setError(err error, now time.Time) {
+	c.mu.Lock()
+	c.lastErr, c.lastErrTime = err, now
+	c.mu.Unlock()
+}
+
+func (c *statsCounters) setFlushed(now time.Time) {
+	c.mu.Lock()
+	c.lastFlush = now
+	c.mu.Unlock()
+}
+
+func (c *statsCounters) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Stats{
+		Handled:       c.handled.Load(),
+		Dropped:       c.dropped.Load(),
+		Retried:       c.retried.Load(),
+		LastError:     c.lastErr,
+		LastErrorTime: c.lastErrTime,
+		LastFlushTime: c.lastFlush,
+	}
+}
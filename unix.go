@@ -0,0 +1,115 @@
+package slogx
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// UnixOptions configures UnixHandler. Zero values select the defaults.
+type UnixOptions struct {
+	slog.HandlerOptions
+
+	// NewHandler creates the handler encoding records to the socket,
+	// slog.NewJSONHandler by default
+	NewHandler func(w io.Writer, opts *slog.HandlerOptions) slog.Handler
+
+	WriteTimeout   time.Duration // 100ms by default
+	ReconnectDelay time.Duration // minimum interval between dial attempts, 1s by default
+	Clock          Clock         // the system clock by default
+}
+
+// UnixHandler writes records to a Unix stream ("unix") or datagram
+// ("unixgram") socket of a node-local log agent.
+// It never blocks longer than the write timeout: records that can not be
+// delivered, e.g. because the agent is absent, are dropped and counted.
+type UnixHandler struct {
+	inner slog.Handler
+	conn  *socketConn
+}
+
+var (
+	_ slog.Handler  = (*UnixHandler)(nil)
+	_ StatsProvider = (*UnixHandler)(nil)
+)
+
+// NewUnixHandler creates UnixHandler for the network ("unix" or "unixgram") and socket path
+func NewUnixHandler(network, path string, opts *UnixOptions) *UnixHandler {
+	if opts == nil {
+		opts = &UnixOptions{}
+	}
+	c := &socketConn{
+		network:        network,
+		addr:           path,
+		dialTimeout:    opts.WriteTimeout,
+		writeTimeout:   opts.WriteTimeout,
+		reconnectDelay: opts.ReconnectDelay,
+		clock:          clockOrSystem(opts.Clock),
+	}
+	if c.writeTimeout <= 0 {
+		c.dialTimeout = 100 * time.Millisecond
+		c.writeTimeout = 100 * time.Millisecond
+	}
+	if c.reconnectDelay <= 0 {
+		c.reconnectDelay = time.Second
+	}
+	newHandler := opts.NewHandler
+	if newHandler == nil {
+		newHandler = func(w io.Writer, opts *slog.HandlerOptions) slog.Handler {
+			return slog.NewJSONHandler(w, opts)
+		}
+	}
+	return &UnixHandler{
+		inner: newHandler(c, &opts.HandlerOptions),
+		conn:  c,
+	}
+}
+
+func (h *UnixHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+// Handle writes the record to the socket. Records which can not be written are
+// dropped without returning an error.
+func (h *UnixHandler) Handle(ctx context.Context, r slog.Record) error {
+	_ = h.inner.Handle(ctx, r)
+	return nil
+}
+
+func (h *UnixHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &UnixHandler{inner: h.inner.WithAttrs(attrs), conn: h.conn}
+}
+
+func (h *UnixHandler) WithGroup(name string) slog.Handler {
+	return &UnixHandler{inner: h.inner.WithGroup(name), conn: h.conn}
+}
+
+// Unwrap returns the handler encoding records to the socket.
+func (h *UnixHandler) Unwrap() slog.Handler {
+	return h.inner
+}
+
+// Dropped returns the number of records dropped since the handler was created.
+func (h *UnixHandler) Dropped() uint64 {
+	return h.conn.dropped.Load()
+}
+
+// Stats returns the delivery counters of the handler.
+func (h *UnixHandler) Stats() Stats {
+	return h.conn.Stats()
+}
+
+func (h *UnixHandler) sharedState() any {
+	return h.conn
+}
+
+// Connected reports whether the handler is connected to the socket.
+func (h *UnixHandler) Connected() bool {
+	return h.conn.connected()
+}
+
+// Close closes the connection.
+func (h *UnixHandler) Close() error {
+	return h.conn.close()
+}
@@ -0,0 +1,83 @@
+package slogx
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUnixHandler(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.sock")
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	h := NewUnixHandler("unix", path, &UnixOptions{
+		NewHandler: func(w io.Writer, opts *slog.HandlerOptions) slog.Handler {
+			return NewLogfmtHandler(w, opts)
+		},
+	})
+	defer h.Close()
+	slog.New(h).With("app", "test").Info("hello", "n", 1)
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(line, "msg=hello app=test n=1") {
+		t.Fatalf("unexpected line %q", line)
+	}
+	if !h.Connected() || h.Stats().Handled != 1 {
+		t.Fatalf("unexpected stats %+v", h.Stats())
+	}
+}
+
+func TestUnixHandlerDatagram(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	h := NewUnixHandler("unixgram", path, nil)
+	defer h.Close()
+	logger := slog.New(h)
+	logger.Info("first")
+	logger.Info("second")
+
+	buf := make([]byte, 1024)
+	for _, msg := range []string{"first", "second"} {
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(buf[:n]), `"msg":"`+msg+`"`) {
+			t.Fatalf("unexpected datagram %q", buf[:n])
+		}
+	}
+}
+
+func TestUnixHandlerAbsentAgent(t *testing.T) {
+	h := NewUnixHandler("unix", filepath.Join(t.TempDir(), "absent.sock"), nil)
+	defer h.Close()
+	if err := h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "lost", 0)); err != nil {
+		t.Fatal(err)
+	}
+	if h.Connected() || h.Dropped() != 1 || h.Stats().LastError == nil {
+		t.Fatalf("unexpected stats %+v", h.Stats())
+	}
+}
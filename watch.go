@@ -0,0 +1,53 @@
+package slogx
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// WatchOptions configures WatchSlow. Zero values select the defaults.
+type WatchOptions struct {
+	Clock Clock // of the elapsed time and the time left to the deadline, the system clock by default
+}
+
+// WatchSlow logs a Warn record if the operation is not stopped within the
+// threshold, or shortly before the deadline of ctx, whichever comes first.
+// It catches hangs that never reach their completion log. The record is
+// logged with ctx, so a slogctx handler adds the context fields to it.
+// The options may be nil.
+//
+//	stop := slogx.WatchSlow(ctx, logger, 5*time.Second, "fetch orders", nil)
+//	defer stop()
+func WatchSlow(ctx context.Context, logger *slog.Logger, threshold time.Duration, operation string, opts *WatchOptions) (stop func()) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if opts == nil {
+		opts = &WatchOptions{}
+	}
+	clock := clockOrSystem(opts.Clock)
+	start := clock.Now()
+	wait, nearDeadline := threshold, false
+	if deadline, ok := ctx.Deadline(); ok {
+		// leave a tenth of the remaining time to report before the deadline
+		if d := deadline.Sub(start) * 9 / 10; d < wait {
+			wait, nearDeadline = d, true
+		}
+	}
+	timer := time.AfterFunc(wait, func() {
+		now := clock.Now()
+		attrs := []any{
+			slog.String("operation", operation),
+			slog.Duration("elapsed", now.Sub(start)),
+			slog.Duration("threshold", threshold),
+		}
+		if deadline, ok := ctx.Deadline(); ok && nearDeadline {
+			attrs = append(attrs, slog.Duration("deadline_in", deadline.Sub(now)))
+		}
+		logger.WarnContext(ctx, "slow operation", attrs...)
+	})
+	return func() {
+		timer.Stop()
+	}
+}
@@ -0,0 +1,75 @@
+package slogx
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is bytes.Buffer safe for the records logged by timers.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestWatchSlow(t *testing.T) {
+	var mu sync.Mutex
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	opts := &WatchOptions{Clock: ClockFunc(func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(2 * time.Second)
+		return now
+	})}
+
+	buf := new(syncBuffer)
+	logger := slog.New(NewLogfmtHandler(buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+
+	stop := WatchSlow(context.Background(), logger, 10*time.Millisecond, "fetch", opts)
+	time.Sleep(50 * time.Millisecond)
+	stop()
+	WatchSlow(context.Background(), logger, time.Hour, "fast", opts)()
+
+	expected := "level=WARN msg=\"slow operation\" operation=fetch elapsed=2s threshold=10ms\n"
+	if buf.String() != expected {
+		t.Fatalf("\n%s\nexpected\n%s", buf, expected)
+	}
+}
+
+func TestWatchSlowDeadline(t *testing.T) {
+	buf := new(syncBuffer)
+	logger := slog.New(NewLogfmtHandler(buf, nil))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	stop := WatchSlow(ctx, logger, time.Hour, "fetch", nil)
+	defer stop()
+	<-ctx.Done()
+	time.Sleep(10 * time.Millisecond)
+	if s := buf.String(); !strings.Contains(s, "threshold=1h0m0s deadline_in=") {
+		t.Fatalf("got %s", s)
+	}
+}